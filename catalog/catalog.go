@@ -0,0 +1,333 @@
+// Package catalog records the event types known to an application along with
+// the Go type, schema version and field layout of each. A catalog may be
+// written to a baseline file and later compared with the registered schema to
+// detect changes which would prevent stored events from being read.
+//
+// Events are registered through Bind by wrapping a serializer:
+//
+//	c := catalog.New()
+//	serializer := c.Serializer("Person", &gob.Serializer{})
+package catalog
+
+import (
+	"context"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+
+	es "github.com/aarongreenlee/eventsource"
+)
+
+// Versioned may be implemented by events to declare the version of their
+// schema. Events which do not implement Versioned are at version 1.
+type Versioned interface {
+	SchemaVersion() int
+}
+
+// Field describes a serialized field of an event. Fields of nested structs
+// are named by their path, such as "Audit.Created".
+type Field struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Entry describes an event type.
+type Entry struct {
+	EventType     string   `json:"eventType"`
+	GoType        string   `json:"goType"`
+	SchemaVersion int      `json:"schemaVersion"`
+	Fields        []Field  `json:"fields"`
+	Aggregates    []string `json:"aggregates,omitempty"`
+}
+
+// Catalog is a registry of event types. A Catalog is safe for concurrent use.
+type Catalog struct {
+	entries map[string]*Entry
+	m       sync.RWMutex
+}
+
+// New builds an empty Catalog.
+func New() *Catalog {
+	return &Catalog{entries: map[string]*Entry{}}
+}
+
+// Register records the events as emitted by the aggregate type. Registering
+// an event type with a second Go type or schema version returns an error and
+// registers none of the events.
+func (c *Catalog) Register(aggregateType string, events ...es.Event) error {
+	return c.register(aggregateType, events, nil)
+}
+
+// register validates every event before recording any of them. When bind is
+// not nil it is called once the events are known to be compatible and the
+// events are only recorded if it succeeds, so the catalog and a serializer
+// never disagree.
+func (c *Catalog) register(aggregateType string, events []es.Event, bind func() error) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	pending := make(map[string]Entry, len(events))
+	entries := make([]Entry, 0, len(events))
+
+	for _, event := range events {
+		entry, err := describe(event)
+		if err != nil {
+			return err
+		}
+
+		existing, ok := pending[entry.EventType]
+		if !ok {
+			if registered, found := c.entries[entry.EventType]; found {
+				existing, ok = *registered, true
+			}
+		}
+
+		if ok && (existing.GoType != entry.GoType || existing.SchemaVersion != entry.SchemaVersion) {
+			return fmt.Errorf("event type %q is registered as %s version %d and can not be registered as %s version %d",
+				entry.EventType, existing.GoType, existing.SchemaVersion, entry.GoType, entry.SchemaVersion)
+		}
+
+		pending[entry.EventType] = entry
+		entries = append(entries, entry)
+	}
+
+	if bind != nil {
+		if err := bind(); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		existing, ok := c.entries[entry.EventType]
+		if !ok {
+			copied := entry
+			existing = &copied
+			c.entries[entry.EventType] = existing
+		}
+
+		if aggregateType != "" && !contains(existing.Aggregates, aggregateType) {
+			existing.Aggregates = append(existing.Aggregates, aggregateType)
+			sort.Strings(existing.Aggregates)
+		}
+	}
+
+	return nil
+}
+
+// Lookup returns the entry registered for the event type.
+func (c *Catalog) Lookup(eventType string) (Entry, bool) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	entry, ok := c.entries[eventType]
+	if !ok {
+		return Entry{}, false
+	}
+
+	return copyEntry(entry), true
+}
+
+// Entries returns every registered entry ordered by event type.
+func (c *Catalog) Entries() []Entry {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	entries := make([]Entry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		entries = append(entries, copyEntry(entry))
+	}
+
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].EventType < entries[b].EventType
+	})
+
+	return entries
+}
+
+// Serializer wraps a serializer so events bound to it are registered with
+// the catalog as emitted by the aggregate type.
+func (c *Catalog) Serializer(aggregateType string, serializer es.Serializer) es.Serializer {
+	return &catalogSerializer{
+		Serializer:    serializer,
+		aggregateType: aggregateType,
+		catalog:       c,
+	}
+}
+
+// catalogSerializer registers events with a catalog as they are bound.
+type catalogSerializer struct {
+	es.Serializer
+	aggregateType string
+	catalog       *Catalog
+}
+
+// Bind implements the eventsource.Serializer interface. The events are bound
+// to the wrapped serializer only if the catalog accepts all of them, and are
+// registered only if the wrapped serializer binds them.
+func (s *catalogSerializer) Bind(events ...es.Event) error {
+	return s.catalog.register(s.aggregateType, events, func() error {
+		return s.Serializer.Bind(events...)
+	})
+}
+
+// MarshalEventContext implements the eventsource.ContextSerializer interface
+// so the context reaches the wrapped serializer.
+func (s *catalogSerializer) MarshalEventContext(ctx context.Context, event es.Event) (es.Record, error) {
+	return es.MarshalEvent(ctx, s.Serializer, event)
+}
+
+// UnmarshalEventContext implements the eventsource.ContextSerializer
+// interface so the context reaches the wrapped serializer.
+func (s *catalogSerializer) UnmarshalEventContext(ctx context.Context, record es.Record) (es.Event, error) {
+	return es.UnmarshalEvent(ctx, s.Serializer, record)
+}
+
+// WriteFile writes the catalog to a JSON baseline file.
+func (c *Catalog) WriteFile(path string) error {
+	data, err := json.MarshalIndent(c.Entries(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode catalog: %w", err)
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// ReadFile reads the entries of a JSON baseline file written by WriteFile.
+func ReadFile(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("unable to decode catalog %s: %w", path, err)
+	}
+
+	return entries, nil
+}
+
+// Check compares the catalog with the baseline file and returns an
+// *IncompatibleError if the registered schema can not read events described
+// by the baseline.
+func (c *Catalog) Check(path string) error {
+	baseline, err := ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if changes := Compare(baseline, c.Entries()); len(changes) > 0 {
+		return &IncompatibleError{Changes: changes}
+	}
+
+	return nil
+}
+
+// describe builds the entry for an event.
+func describe(event es.Event) (Entry, error) {
+	if event == nil {
+		return Entry{}, fmt.Errorf("unable to register a nil event")
+	}
+
+	eventType := event.EventType()
+	if eventType == "" {
+		return Entry{}, fmt.Errorf("unable to determine event type of %T", event)
+	}
+
+	version := 1
+	if v, ok := event.(Versioned); ok {
+		version = v.SchemaVersion()
+	}
+
+	t := reflect.TypeOf(event)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return Entry{
+		EventType:     eventType,
+		GoType:        t.PkgPath() + "." + t.Name(),
+		SchemaVersion: version,
+		Fields:        fields(t, "", map[reflect.Type]bool{}),
+	}, nil
+}
+
+var (
+	binaryMarshaler = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	textMarshaler   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	gobEncoder      = reflect.TypeOf((*gob.GobEncoder)(nil)).Elem()
+)
+
+// fields lists the exported fields of a struct type. Embedded structs are
+// flattened and nested structs are described by path unless they encode
+// themselves, as time.Time does.
+func fields(t reflect.Type, prefix string, seen map[reflect.Type]bool) []Field {
+	if t.Kind() != reflect.Struct || seen[t] {
+		return nil
+	}
+
+	seen[t] = true
+	defer delete(seen, t)
+
+	var found []Field
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if f.Anonymous && ft.Kind() == reflect.Struct {
+			found = append(found, fields(ft, prefix, seen)...)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := prefix + f.Name
+
+		if ft.Kind() == reflect.Struct && !encodesItself(ft) {
+			found = append(found, fields(ft, name+".", seen)...)
+			continue
+		}
+
+		found = append(found, Field{Name: name, Type: f.Type.String()})
+	}
+
+	return found
+}
+
+// encodesItself reports whether values of the type control their own
+// encoding.
+func encodesItself(t reflect.Type) bool {
+	p := reflect.PointerTo(t)
+	return p.Implements(binaryMarshaler) || p.Implements(textMarshaler) || p.Implements(gobEncoder)
+}
+
+func copyEntry(entry *Entry) Entry {
+	copied := *entry
+	copied.Fields = append([]Field(nil), entry.Fields...)
+	copied.Aggregates = append([]string(nil), entry.Aggregates...)
+	return copied
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
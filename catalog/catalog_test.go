@@ -0,0 +1,163 @@
+package catalog_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/catalog"
+	"github.com/aarongreenlee/eventsource/serializer/gob"
+)
+
+type Audit struct {
+	By string
+	At time.Time
+}
+
+type Opened struct {
+	es.EventModel
+	Owner string
+	Audit Audit
+}
+
+func (Opened) EventType() string { return "opened" }
+
+type Closed struct {
+	es.EventModel
+	Reason string
+}
+
+func (Closed) EventType() string { return "closed" }
+
+func (Closed) SchemaVersion() int { return 2 }
+
+// TestSerializerRegisters asserts events bound to a wrapped serializer are
+// described by the catalog.
+func TestSerializerRegisters(t *testing.T) {
+	c := catalog.New()
+
+	require.NoError(t, c.Serializer("Account", &gob.Serializer{}).Bind(&Opened{}, &Closed{}))
+	require.NoError(t, c.Serializer("Ledger", &gob.Serializer{}).Bind(Opened{}))
+
+	opened, ok := c.Lookup("opened")
+	require.True(t, ok)
+	assert.Equal(t, "github.com/aarongreenlee/eventsource/catalog_test.Opened", opened.GoType)
+	assert.Equal(t, 1, opened.SchemaVersion)
+	assert.Equal(t, []string{"Account", "Ledger"}, opened.Aggregates)
+	assert.Equal(t, []catalog.Field{
+		{Name: "ID", Type: "string"},
+		{Name: "Version", Type: "int64"},
+		{Name: "At", Type: "time.Time"},
+		{Name: "Owner", Type: "string"},
+		{Name: "Audit.By", Type: "string"},
+		{Name: "Audit.At", Type: "time.Time"},
+	}, opened.Fields)
+
+	closed, ok := c.Lookup("closed")
+	require.True(t, ok)
+	assert.Equal(t, 2, closed.SchemaVersion)
+
+	entries := c.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "closed", entries[0].EventType)
+}
+
+// TestCompare asserts incompatible changes are detected.
+func TestCompare(t *testing.T) {
+	baseline := []catalog.Entry{
+		{EventType: "opened", SchemaVersion: 1, Fields: []catalog.Field{{Name: "Owner", Type: "string"}, {Name: "Limit", Type: "int"}}},
+		{EventType: "closed", SchemaVersion: 1, Fields: []catalog.Field{{Name: "Reason", Type: "string"}}},
+		{EventType: "renamed", SchemaVersion: 2},
+		{EventType: "frozen", SchemaVersion: 1},
+	}
+
+	current := []catalog.Entry{
+		// Owner removed and Limit retyped without a version change.
+		{EventType: "opened", SchemaVersion: 1, Fields: []catalog.Field{{Name: "Limit", Type: "int64"}, {Name: "Added", Type: "bool"}}},
+		// A new version acknowledges the breaking change.
+		{EventType: "closed", SchemaVersion: 2},
+		{EventType: "renamed", SchemaVersion: 1},
+	}
+
+	var reasons []string
+	for _, change := range catalog.Compare(baseline, current) {
+		reasons = append(reasons, change.String())
+	}
+
+	assert.Equal(t, []string{
+		"opened.Owner: field removed without a schema version change",
+		"opened.Limit: field type changed from int to int64 without a schema version change",
+		"renamed: schema version decreased from 2 to 1",
+		"frozen: event type removed",
+	}, reasons)
+}
+
+// TestCheck asserts a catalog is compatible with the baseline it wrote and
+// incompatible once an event type it described is missing.
+func TestCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.json")
+
+	c := catalog.New()
+	require.NoError(t, c.Register("Account", &Opened{}, &Closed{}))
+	require.NoError(t, c.WriteFile(path))
+
+	catalog.AssertCompatible(t, c, path)
+
+	smaller := catalog.New()
+	require.NoError(t, smaller.Register("Account", &Opened{}))
+
+	var incompatible *catalog.IncompatibleError
+	assert.True(t, errors.As(smaller.Check(path), &incompatible))
+}
+
+// TestRegisterConflict asserts an event type may only describe one Go type.
+func TestRegisterConflict(t *testing.T) {
+	c := catalog.New()
+	require.NoError(t, c.Register("Account", &Opened{}))
+
+	type Impostor struct {
+		Opened
+	}
+
+	assert.Error(t, c.Register("Account", &Impostor{}))
+}
+
+// TestRegisterAtomic asserts a conflicting event leaves the catalog and the
+// wrapped serializer unchanged.
+func TestRegisterAtomic(t *testing.T) {
+	type Impostor struct {
+		Opened
+	}
+
+	c := catalog.New()
+	require.NoError(t, c.Register("Account", &Opened{}))
+
+	assert.Error(t, c.Register("Ledger", &Closed{}, &Impostor{}))
+	_, ok := c.Lookup("closed")
+	assert.False(t, ok)
+
+	inner := &gob.Serializer{}
+	assert.Error(t, c.Serializer("Ledger", inner).Bind(&Closed{}, &Impostor{}))
+	_, err := inner.MarshalEvent(&Closed{})
+	assert.True(t, errors.Is(err, es.ErrUnboundEventType))
+
+	// Events rejected by the wrapped serializer are not registered.
+	type Shut struct {
+		Closed
+	}
+
+	inner = &gob.Serializer{}
+	require.NoError(t, inner.Bind(&Shut{}))
+	assert.Error(t, c.Serializer("Ledger", inner).Bind(&Closed{}))
+	_, ok = c.Lookup("closed")
+	assert.False(t, ok)
+
+	opened, ok := c.Lookup("opened")
+	require.True(t, ok)
+	assert.Equal(t, []string{"Account"}, opened.Aggregates)
+}
@@ -0,0 +1,92 @@
+package catalog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Change describes a difference between a baseline entry and the registered
+// schema which prevents stored events from being read.
+type Change struct {
+	EventType string
+	Field     string
+	Reason    string
+}
+
+// String describes the change.
+func (c Change) String() string {
+	if c.Field == "" {
+		return fmt.Sprintf("%s: %s", c.EventType, c.Reason)
+	}
+	return fmt.Sprintf("%s.%s: %s", c.EventType, c.Field, c.Reason)
+}
+
+// IncompatibleError lists the incompatible changes found by Check.
+type IncompatibleError struct {
+	Changes []Change
+}
+
+// Error implements the standard go Error interface.
+func (e *IncompatibleError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d incompatible event schema change(s)", len(e.Changes))
+	for _, change := range e.Changes {
+		b.WriteString("\n\t")
+		b.WriteString(change.String())
+	}
+	return b.String()
+}
+
+// Compare lists the incompatible changes from the baseline to the current
+// entries. Within a schema version fields may be added but not removed or
+// retyped. Increasing the schema version of an event type acknowledges a
+// breaking change; decreasing it or removing the event type does not.
+//
+// A change of GoType alone is compatible: the bundled serializers resolve
+// stored records by event type, or message name, rather than by Go type.
+func Compare(baseline, current []Entry) []Change {
+	index := make(map[string]Entry, len(current))
+	for _, entry := range current {
+		index[entry.EventType] = entry
+	}
+
+	var changes []Change
+
+	for _, old := range baseline {
+		entry, ok := index[old.EventType]
+		switch {
+		case !ok:
+			changes = append(changes, Change{EventType: old.EventType, Reason: "event type removed"})
+			continue
+		case entry.SchemaVersion < old.SchemaVersion:
+			changes = append(changes, Change{
+				EventType: old.EventType,
+				Reason:    fmt.Sprintf("schema version decreased from %d to %d", old.SchemaVersion, entry.SchemaVersion),
+			})
+			continue
+		case entry.SchemaVersion > old.SchemaVersion:
+			continue
+		}
+
+		fields := make(map[string]string, len(entry.Fields))
+		for _, f := range entry.Fields {
+			fields[f.Name] = f.Type
+		}
+
+		for _, f := range old.Fields {
+			t, ok := fields[f.Name]
+			switch {
+			case !ok:
+				changes = append(changes, Change{EventType: old.EventType, Field: f.Name, Reason: "field removed without a schema version change"})
+			case t != f.Type:
+				changes = append(changes, Change{
+					EventType: old.EventType,
+					Field:     f.Name,
+					Reason:    fmt.Sprintf("field type changed from %s to %s without a schema version change", f.Type, t),
+				})
+			}
+		}
+	}
+
+	return changes
+}
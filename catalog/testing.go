@@ -0,0 +1,39 @@
+package catalog
+
+import (
+	"os"
+	"testing"
+)
+
+// UpdateEnv names the environment variable which, when set to any value,
+// makes AssertCompatible write the baseline file instead of checking it.
+const UpdateEnv = "EVENTSOURCE_UPDATE_CATALOG"
+
+// AssertCompatible fails the test when the catalog can not read events
+// described by the baseline file. The baseline is (re)written when the
+// UpdateEnv environment variable is set.
+//
+//	func TestEventCatalog(t *testing.T) {
+//		c := catalog.New()
+//		_, err := person.NewService(repository.WithCatalog(c))
+//		require.NoError(t, err)
+//
+//		catalog.AssertCompatible(t, c, "testdata/events.json")
+//	}
+func AssertCompatible(t testing.TB, c *Catalog, path string) {
+	t.Helper()
+
+	if _, ok := os.LookupEnv(UpdateEnv); ok {
+		if err := c.WriteFile(path); err != nil {
+			t.Fatalf("unable to write event catalog baseline: %s", err)
+		}
+		return
+	}
+
+	if err := c.Check(path); err != nil {
+		if os.IsNotExist(err) {
+			t.Fatalf("event catalog baseline %s does not exist; set %s=1 to create it", path, UpdateEnv)
+		}
+		t.Fatalf("event catalog is incompatible with %s: %s", path, err)
+	}
+}
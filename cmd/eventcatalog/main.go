@@ -0,0 +1,48 @@
+// Command eventcatalog compares two event catalog files written by
+// catalog.Catalog.WriteFile and exits with a non-zero status when the current
+// catalog can not read events described by the baseline.
+//
+//	eventcatalog -baseline main/events.json -current events.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aarongreenlee/eventsource/catalog"
+)
+
+func main() {
+	baselinePath := flag.String("baseline", "", "path to the baseline catalog")
+	currentPath := flag.String("current", "", "path to the current catalog")
+	flag.Parse()
+
+	if *baselinePath == "" || *currentPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	baseline, err := catalog.ReadFile(*baselinePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading baseline: %s\n", err)
+		os.Exit(2)
+	}
+
+	current, err := catalog.ReadFile(*currentPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading current catalog: %s\n", err)
+		os.Exit(2)
+	}
+
+	changes := catalog.Compare(baseline, current)
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+
+	if len(changes) > 0 {
+		os.Exit(1)
+	}
+
+	fmt.Printf("%d event type(s) compatible\n", len(baseline))
+}
@@ -1,6 +1,9 @@
 package eventsource
 
-import "context"
+import (
+	"context"
+	"fmt"
+)
 
 // Command encapsulates the data to mutate an aggregate.
 //
@@ -17,10 +20,46 @@ type Command interface {
 	EventType() string
 }
 
+// Expectation declares whether a command requires its aggregate to exist
+// before the command is applied.
+type Expectation int
+
+const (
+	// ExpectAny applies the command whether or not the aggregate exists.
+	ExpectAny Expectation = iota
+
+	// ExpectNew applies the command only when the aggregate has no history.
+	ExpectNew
+
+	// ExpectExists applies the command only when the aggregate has history.
+	ExpectExists
+)
+
+// String implements fmt.Stringer.
+func (e Expectation) String() string {
+	switch e {
+	case ExpectAny:
+		return "any"
+	case ExpectNew:
+		return "new"
+	case ExpectExists:
+		return "exists"
+	default:
+		return fmt.Sprintf("Expectation(%d)", int(e))
+	}
+}
+
+// Expecter may be implemented by a Command to declare an Expectation.
+// Commands which do not implement Expecter are applied with ExpectAny.
+type Expecter interface {
+	Expectation() Expectation
+}
+
 // CommandModel provides an embeddable struct that implements Command.
 type CommandModel struct {
-	ID   string
-	Type string
+	ID     string
+	Type   string
+	Expect Expectation
 }
 
 // AggregateID implements the Command interface; returns the aggregate id
@@ -32,6 +71,11 @@ func (m CommandModel) EventType() string {
 	return m.Type
 }
 
+// Expectation implements the Expecter interface.
+func (m CommandModel) Expectation() Expectation {
+	return m.Expect
+}
+
 // CommandHandler consumes a command and emits Events
 type CommandHandler interface {
 	// Apply applies a command to an aggregate to generate a new set of events
@@ -0,0 +1,212 @@
+// Package dispatch routes the events and commands of an aggregate to handlers
+// registered for each concrete type. It replaces hand written type switches
+// within Aggregate.On and CommandHandler.Apply.
+//
+// Handlers are registered for either the value or the pointer form of a type
+// and receive the form they were registered for regardless of the form
+// dispatched. A serializer returning *CreateEvent therefore reaches a handler
+// registered for CreateEvent.
+package dispatch
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	es "github.com/aarongreenlee/eventsource"
+)
+
+// ErrUnhandled is returned when no handler has been registered for an event
+// or command.
+const ErrUnhandled = es.Error("unhandled")
+
+// EventHandler folds an event of type E into an aggregate of type A.
+type EventHandler[A any, E es.Event] func(aggregate *A, event E) error
+
+// CommandHandler applies a command of type C to an aggregate of type A to
+// generate a new set of events.
+type CommandHandler[A any, C es.Command] func(ctx context.Context, aggregate *A, command C) ([]es.Event, error)
+
+// Registration adds a handler to a Dispatcher.
+type Registration[A any] func(d *Dispatcher[A]) error
+
+// Dispatcher routes events and commands to the handlers registered for
+// aggregates of type A. A Dispatcher is safe for concurrent use once built.
+type Dispatcher[A any] struct {
+	events   map[reflect.Type]func(aggregate *A, event es.Event) error
+	commands map[reflect.Type]func(ctx context.Context, aggregate *A, command es.Command) ([]es.Event, error)
+	required []es.Event
+}
+
+// New builds a Dispatcher from the registrations provided. An error is
+// returned if a type is registered more than once or if an event declared
+// with Events has no handler.
+func New[A any](registrations ...Registration[A]) (*Dispatcher[A], error) {
+	d := &Dispatcher[A]{
+		events:   make(map[reflect.Type]func(*A, es.Event) error),
+		commands: make(map[reflect.Type]func(context.Context, *A, es.Command) ([]es.Event, error)),
+	}
+
+	for _, register := range registrations {
+		if err := register(d); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := d.Verify(d.required...); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Must is a helper that wraps a call to New and panics if the error is
+// non-nil. It is intended for package level variables.
+func Must[A any](d *Dispatcher[A], err error) *Dispatcher[A] {
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Event registers a handler for events of type E.
+func Event[A any, E es.Event](handler EventHandler[A, E]) Registration[A] {
+	return func(d *Dispatcher[A]) error {
+		t := baseType(reflect.TypeOf((*E)(nil)).Elem())
+		if _, ok := d.events[t]; ok {
+			return fmt.Errorf("event %s registered more than once", t)
+		}
+
+		d.events[t] = func(aggregate *A, event es.Event) error {
+			e, ok := normalize[E](event)
+			if !ok {
+				return fmt.Errorf("%w: unable to convert event %T to %s", ErrUnhandled, event, t)
+			}
+			return handler(aggregate, e)
+		}
+
+		return nil
+	}
+}
+
+// Events declares events which must have a registered handler, typically the
+// events bound to the aggregate's repository. New reports any without one so
+// missing handlers are found when the Dispatcher is built.
+func Events[A any](events ...es.Event) Registration[A] {
+	return func(d *Dispatcher[A]) error {
+		d.required = append(d.required, events...)
+		return nil
+	}
+}
+
+// Command registers a handler for commands of type C.
+func Command[A any, C es.Command](handler CommandHandler[A, C]) Registration[A] {
+	return func(d *Dispatcher[A]) error {
+		t := baseType(reflect.TypeOf((*C)(nil)).Elem())
+		if _, ok := d.commands[t]; ok {
+			return fmt.Errorf("command %s registered more than once", t)
+		}
+
+		d.commands[t] = func(ctx context.Context, aggregate *A, command es.Command) ([]es.Event, error) {
+			c, ok := normalize[C](command)
+			if !ok {
+				return nil, fmt.Errorf("%w: unable to convert command %T to %s", ErrUnhandled, command, t)
+			}
+			return handler(ctx, aggregate, c)
+		}
+
+		return nil
+	}
+}
+
+// On folds the event into the aggregate using the handler registered for the
+// event's type.
+func (d *Dispatcher[A]) On(aggregate *A, event es.Event) error {
+	if event == nil {
+		return fmt.Errorf("%w: nil event", ErrUnhandled)
+	}
+
+	handler, ok := d.events[baseType(reflect.TypeOf(event))]
+	if !ok {
+		return fmt.Errorf("%w: event %q, %T, is not registered for %T", ErrUnhandled, event.EventType(), event, aggregate)
+	}
+
+	return handler(aggregate, event)
+}
+
+// Apply applies the command to the aggregate using the handler registered for
+// the command's type.
+func (d *Dispatcher[A]) Apply(ctx context.Context, aggregate *A, command es.Command) ([]es.Event, error) {
+	if command == nil {
+		return nil, fmt.Errorf("%w: nil command", ErrUnhandled)
+	}
+
+	handler, ok := d.commands[baseType(reflect.TypeOf(command))]
+	if !ok {
+		return nil, fmt.Errorf("%w: command %q, %T, is not registered for %T", ErrUnhandled, command.EventType(), command, aggregate)
+	}
+
+	return handler(ctx, aggregate, command)
+}
+
+// Verify reports every event without a registered handler. New calls it with
+// the events declared by Events; it may also be called with the events bound
+// to a repository so missing handlers are found when the repository is built
+// rather than when history is loaded.
+func (d *Dispatcher[A]) Verify(events ...es.Event) error {
+	var missing []string
+
+	for _, event := range events {
+		if event == nil {
+			continue
+		}
+		t := baseType(reflect.TypeOf(event))
+		if _, ok := d.events[t]; !ok {
+			missing = append(missing, fmt.Sprintf("%q (%s)", event.EventType(), t))
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+
+	return fmt.Errorf("%w: %T has no handler for event(s) %s", ErrUnhandled, new(A), strings.Join(missing, ", "))
+}
+
+// baseType removes a single level of pointer indirection.
+func baseType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// normalize converts v to T when v is either T, a pointer to T or, when T is
+// a pointer, the value T points to.
+func normalize[T any](v interface{}) (T, bool) {
+	if t, ok := v.(T); ok {
+		return t, true
+	}
+
+	var zero T
+	want := reflect.TypeOf((*T)(nil)).Elem()
+	rv := reflect.ValueOf(v)
+
+	switch {
+	case rv.Kind() == reflect.Ptr && rv.Type().Elem() == want:
+		if rv.IsNil() {
+			return zero, false
+		}
+		return rv.Elem().Interface().(T), true
+	case want.Kind() == reflect.Ptr && want.Elem() == rv.Type():
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		return p.Interface().(T), true
+	}
+
+	return zero, false
+}
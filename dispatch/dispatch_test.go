@@ -0,0 +1,121 @@
+package dispatch_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/dispatch"
+)
+
+type Light struct {
+	On bool
+}
+
+type SwitchedOn struct {
+	es.EventModel
+}
+
+func (SwitchedOn) EventType() string { return "switchedOn" }
+
+type SwitchedOff struct {
+	es.EventModel
+}
+
+func (SwitchedOff) EventType() string { return "switchedOff" }
+
+type Toggle struct {
+	es.CommandModel
+}
+
+func newDispatcher(t *testing.T) *dispatch.Dispatcher[Light] {
+	d, err := dispatch.New(
+		dispatch.Event(func(l *Light, _ SwitchedOn) error {
+			l.On = true
+			return nil
+		}),
+		dispatch.Event(func(l *Light, _ *SwitchedOff) error {
+			l.On = false
+			return nil
+		}),
+		dispatch.Command(func(_ context.Context, l *Light, _ *Toggle) ([]es.Event, error) {
+			if l.On {
+				return []es.Event{&SwitchedOff{}}, nil
+			}
+			return []es.Event{&SwitchedOn{}}, nil
+		}),
+	)
+	require.NoError(t, err)
+	return d
+}
+
+// TestDispatchNormalizesForms asserts handlers receive events and commands
+// in either their value or pointer form.
+func TestDispatchNormalizesForms(t *testing.T) {
+	d := newDispatcher(t)
+	light := &Light{}
+
+	require.NoError(t, d.On(light, &SwitchedOn{}))
+	assert.True(t, light.On)
+
+	require.NoError(t, d.On(light, SwitchedOff{}))
+	assert.False(t, light.On)
+
+	require.NoError(t, d.On(light, SwitchedOn{}))
+	require.NoError(t, d.On(light, &SwitchedOff{}))
+	assert.False(t, light.On)
+
+	events, err := d.Apply(context.Background(), light, Toggle{})
+	require.NoError(t, err)
+	assert.IsType(t, &SwitchedOn{}, events[0])
+}
+
+// TestDispatchUnhandled asserts unregistered types are reported.
+func TestDispatchUnhandled(t *testing.T) {
+	d, err := dispatch.New(
+		dispatch.Event(func(l *Light, _ SwitchedOn) error { return nil }),
+	)
+	require.NoError(t, err)
+
+	err = d.On(&Light{}, SwitchedOff{})
+	assert.True(t, errors.Is(err, dispatch.ErrUnhandled))
+
+	_, err = d.Apply(context.Background(), &Light{}, Toggle{})
+	assert.True(t, errors.Is(err, dispatch.ErrUnhandled))
+
+	err = d.Verify(SwitchedOn{}, &SwitchedOff{})
+	assert.True(t, errors.Is(err, dispatch.ErrUnhandled))
+	assert.Contains(t, err.Error(), "switchedOff")
+
+	assert.NoError(t, d.Verify(&SwitchedOn{}))
+}
+
+// TestDispatchDuplicate asserts a type may only be registered once.
+func TestDispatchDuplicate(t *testing.T) {
+	_, err := dispatch.New(
+		dispatch.Event(func(l *Light, _ SwitchedOn) error { return nil }),
+		dispatch.Event(func(l *Light, _ *SwitchedOn) error { return nil }),
+	)
+	assert.Error(t, err)
+}
+
+// TestDispatchEvents asserts New fails when a declared event has no handler.
+func TestDispatchEvents(t *testing.T) {
+	_, err := dispatch.New(
+		dispatch.Events[Light](SwitchedOn{}, SwitchedOff{}),
+		dispatch.Event(func(l *Light, _ SwitchedOn) error { return nil }),
+	)
+	assert.True(t, errors.Is(err, dispatch.ErrUnhandled))
+	assert.Contains(t, err.Error(), "switchedOff")
+
+	_, err = dispatch.New(
+		dispatch.Events[Light](SwitchedOn{}, &SwitchedOff{}),
+		dispatch.Event(func(l *Light, _ SwitchedOn) error { return nil }),
+		dispatch.Event(func(l *Light, _ *SwitchedOff) error { return nil }),
+	)
+	assert.NoError(t, err)
+}
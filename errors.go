@@ -5,11 +5,37 @@ const (
 	// aggregate could not be found.
 	ErrNotFound = Error("not found")
 
+	// ErrConflict should be returned by store implementations when the
+	// records being saved collide with records already stored, typically
+	// because another writer has saved the same version first.
+	ErrConflict = Error("conflict")
+
+	// ErrVersionGap should be returned by store implementations when the
+	// records being saved do not continue from the latest stored version.
+	ErrVersionGap = Error("version gap")
+
+	// ErrAlreadyExists is returned when a command expecting a new aggregate
+	// is applied to an aggregate which already has history.
+	ErrAlreadyExists = Error("already exists")
+
+	// ErrBusy is returned when a command can not be queued because too many
+	// commands are already waiting for the same aggregate.
+	ErrBusy = Error("busy")
+
 	// ErrNoEventsProduced is returned when changes are applied to produce a
 	// new aggregate but the operation results in no new events being
 	// produced. Such a condition may not be unexpected depending on the
 	// context.
 	ErrNoEventsProduced = Error("no events produced")
+
+	// ErrUnboundEventType should be returned by serializer implementations
+	// when asked to unmarshal a record of an event type which has not been
+	// bound.
+	ErrUnboundEventType = Error("unbound event type")
+
+	// ErrTxUnsupported is returned when records for several aggregates must
+	// be saved atomically but the store does not implement TxStore.
+	ErrTxUnsupported = Error("store does not support multi-aggregate transactions")
 )
 
 // Type Error implements the Error interface and is allows for errors to be
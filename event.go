@@ -29,3 +29,44 @@ type Event interface {
 	// past tense (e.g., "changedName")
 	EventType() string
 }
+
+// Stamper may be implemented by events which allow the Repository to assign
+// the aggregate id, version and timestamp when the event is produced.
+type Stamper interface {
+	// Stamp implementations should record the aggregate id and version
+	// provided. The timestamp should be recorded only if one was not set.
+	Stamp(aggregateID string, version int64, at time.Time)
+}
+
+// EventModel provides an embeddable struct that implements all of the Event
+// interface except EventType, which the embedding event provides. Events
+// embedding EventModel implement Stamper when produced as pointers.
+type EventModel struct {
+	ID      string
+	Version int64
+	At      time.Time
+}
+
+// AggregateID implements the Event interface; returns the aggregate id
+func (m EventModel) AggregateID() string {
+	return m.ID
+}
+
+// EventVersion implements the Event interface; returns the event version
+func (m EventModel) EventVersion() int64 {
+	return m.Version
+}
+
+// EventAt implements the Event interface; returns the event timestamp
+func (m EventModel) EventAt() time.Time {
+	return m.At
+}
+
+// Stamp implements the Stamper interface.
+func (m *EventModel) Stamp(aggregateID string, version int64, at time.Time) {
+	m.ID = aggregateID
+	m.Version = version
+	if m.At.IsZero() {
+		m.At = at
+	}
+}
@@ -42,8 +42,9 @@ func (s Service) Create(ctx context.Context, req CreateRequest) (CreateResponse,
 
 	cmd := CreateCommand{
 		CommandModel: eventsource.CommandModel{
-			ID:   idgen.NewID(),
-			Type: CreateCommandKey,
+			ID:     idgen.NewID(),
+			Type:   CreateCommandKey,
+			Expect: eventsource.ExpectNew,
 		},
 		Data: CreateEvent{
 			Name:  req.Name,
@@ -56,18 +57,13 @@ func (s Service) Create(ctx context.Context, req CreateRequest) (CreateResponse,
 		},
 	}
 
-	version, err := s.repo.Apply(ctx, cmd)
+	result, err := s.repo.ApplyResult(ctx, cmd)
 	if err != nil {
 		return CreateResponse{}, fmt.Errorf("error creating Person: %w", err)
 	}
 
 	return CreateResponse{
-		Person: Person{
-			ID:      cmd.CommandModel.ID,
-			Email:   cmd.Data.Email,
-			Name:    cmd.Data.Name,
-			Version: version,
-		},
+		Person: *result.Aggregate,
 	}, nil
 }
 
@@ -81,8 +77,7 @@ type CreateCommand struct {
 // CreateEvent is the event data produced by a accepted CreateCommand
 // and is serialized and persisted by the eventsource.Repository.
 type CreateEvent struct {
-	ID      string
-	Version int64
+	eventsource.EventModel
 
 	Name  string
 	Email string
@@ -100,21 +95,6 @@ func (e CreateEvent) on(p *Person) error {
 	return nil
 }
 
-// AggregateID implements the eventsource.Event interface.
-func (e CreateEvent) AggregateID() string {
-	return e.ID
-}
-
-// EventVersion implements the eventsource.Event interface.
-func (e CreateEvent) EventVersion() int64 {
-	return e.Version
-}
-
-// EventAt implements the eventsource.Event interface.
-func (e CreateEvent) EventAt() time.Time {
-	return e.Audit.Created
-}
-
 // EventType implements the eventsource.Event interface.
 func (e CreateEvent) EventType() string {
 	return CreatedEventKey
@@ -152,12 +132,12 @@ func (cmd *CreateCommand) apply(p *Person) ([]eventsource.Event, error) {
 
 	// Having passed all validations we are ready to produce our event.
 	// At this point, our language will change from "create" to "created".
+	// The repository stamps the event with the aggregate id, version and
+	// timestamp.
 	event := &CreateEvent{
-		ID:      cmd.AggregateID(),
-		Name:    cmd.Data.Name,
-		Email:   cmd.Data.Email,
-		Audit:   cmd.Data.Audit,
-		Version: 1, // create events are always v1
+		Name:  cmd.Data.Name,
+		Email: cmd.Data.Email,
+		Audit: cmd.Data.Audit,
 	}
 
 	return []eventsource.Event{event}, nil
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/dispatch"
 	"github.com/aarongreenlee/eventsource/example/audit"
 )
 
@@ -42,19 +43,34 @@ func (p Person) ValidateEmail(s string) error {
 	return nil
 }
 
+// events lists every event stored for a Person.
+var events = []eventsource.Event{
+	CreateEvent{},
+}
+
+// dispatcher routes the events and commands of a Person to their handlers.
+// Handlers receive the form registered, value or pointer, regardless of the
+// form returned by a command or produced by the serializer. Every event in
+// events must be handled so a stored Person can always be loaded.
+var dispatcher = dispatch.Must(dispatch.New(
+	dispatch.Events[Person](events...),
+	dispatch.Event(func(p *Person, e CreateEvent) error {
+		return e.on(p)
+	}),
+	dispatch.Command(func(_ context.Context, p *Person, cmd CreateCommand) ([]eventsource.Event, error) {
+		return cmd.apply(p)
+	}),
+))
+
 // Apply is our write handler and implements the `eventsource.CommandHandler` interface and is called
-// when we wish to execute a command. Each command is implemented individually.
+// when we wish to execute a command. Each command is implemented individually
+// and registered with the dispatcher.
 //
 // Each command should validate that the change can be applied to the current
 // state. An accepted command results in one or more events being returned
 // which will be persisted by the eventsource Repository.
-func (p *Person) Apply(_ context.Context, cmd eventsource.Command) ([]eventsource.Event, error) {
-	switch v := cmd.(type) {
-	case CreateCommand:
-		return v.apply(p)
-	}
-
-	return nil, fmt.Errorf("the command %q is not implemented on %T", cmd.EventType(), p)
+func (p *Person) Apply(ctx context.Context, cmd eventsource.Command) ([]eventsource.Event, error) {
+	return dispatcher.Apply(ctx, p, cmd)
 }
 
 // On is our read handler and implements the `eventsource.Aggregate` interface
@@ -63,17 +79,5 @@ func (p *Person) Apply(_ context.Context, cmd eventsource.Command) ([]eventsourc
 // history where they are applied to produce the Aggregate. In functional terms,
 // this is a left-fold over events when reading the record.
 func (p *Person) On(event eventsource.Event) error {
-	switch event.EventType() {
-	case CreatedEventKey:
-		e, ok := event.(CreateEvent)
-		if !ok {
-			return fmt.Errorf("could not cast for event %q", CreatedEventKey)
-		}
-		if err := e.on(p); err != nil {
-			return err
-		}
-		return nil
-	}
-
-	return fmt.Errorf("the command %q is not implemented on %T", event.EventType(), p)
+	return dispatcher.On(p, event)
 }
@@ -2,20 +2,19 @@ package person
 
 import (
 	"context"
-	"fmt"
 
-	"github.com/aarongreenlee/eventsource"
 	"github.com/aarongreenlee/eventsource/repository"
+	"github.com/aarongreenlee/eventsource/repository/typed"
 )
 
 func NewService(repoOpts ...repository.Option) (*Service, error) {
-	repo, err := repository.New(
-		&Person{},
-		[]eventsource.Event{
-			CreateEvent{},
-		},
-		repoOpts...,
-	)
+	// Commands only describe the state change; the repository assigns the
+	// aggregate id, version and timestamp of each event. The self-check
+	// verifies every event survives the serializer and is handled by On
+	// before the service starts.
+	opts := append([]repository.Option{repository.WithStamping(), repository.WithSelfCheck()}, repoOpts...)
+
+	repo, err := typed.New[Person](events, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -24,19 +23,14 @@ func NewService(repoOpts ...repository.Option) (*Service, error) {
 }
 
 type Service struct {
-	repo *repository.Repository
+	repo *typed.Repository[Person]
 }
 
 func (s Service) Load(ctx context.Context, aggregateID string) (Person, error) {
-	aggregate, err := s.repo.Load(ctx, aggregateID)
+	p, err := s.repo.Load(ctx, aggregateID)
 	if err != nil {
 		return Person{}, err
 	}
 
-	p, ok := aggregate.(*Person)
-	if !ok {
-		return Person{}, fmt.Errorf("unable to cast aggregate to %T", Person{})
-	}
-
 	return *p, nil
 }
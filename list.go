@@ -0,0 +1,73 @@
+package eventsource
+
+import (
+	"context"
+	"time"
+)
+
+// Lister may be implemented by a Store which is able to enumerate the
+// aggregates it holds.
+type Lister interface {
+	// List implementations should return the aggregates matching the query
+	// ordered by aggregate ID. When more aggregates match than the query
+	// Limit allows, ListPage.Next holds the token for the following page.
+	List(ctx context.Context, query ListQuery) (ListPage, error)
+}
+
+// ListQuery filters and pages the aggregates returned by Lister.List. Zero
+// valued filters match every aggregate.
+type ListQuery struct {
+	// AggregateType matches aggregates whose records were saved with the
+	// same Record.AggregateType.
+	AggregateType string
+
+	// CreatedAfter and CreatedBefore bound, exclusively, the time the first
+	// record of an aggregate was saved.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+
+	// MinVersion and MaxVersion bound, inclusively, the last version saved.
+	MinVersion int64
+	MaxVersion int64
+
+	// Limit caps the number of aggregates returned. A Limit of 0 returns every
+	// matching aggregate.
+	Limit int
+
+	// Token resumes listing from ListPage.Next of a previous page.
+	Token string
+}
+
+// ListPage is a page of aggregates returned by Lister.List.
+type ListPage struct {
+	Aggregates []AggregateInfo
+
+	// Next is the token of the following page or empty on the last page.
+	Next string
+}
+
+// AggregateInfo summarizes an aggregate held by a Store.
+type AggregateInfo struct {
+	AggregateID   string
+	AggregateType string
+	CreatedAt     time.Time
+	Version       int64
+}
+
+// Matches reports whether the aggregate satisfies the filters of the query.
+// Paging is left to the caller.
+func (q ListQuery) Matches(info AggregateInfo) bool {
+	switch {
+	case q.AggregateType != "" && info.AggregateType != q.AggregateType:
+		return false
+	case !q.CreatedAfter.IsZero() && !info.CreatedAt.After(q.CreatedAfter):
+		return false
+	case !q.CreatedBefore.IsZero() && !info.CreatedAt.Before(q.CreatedBefore):
+		return false
+	case q.MinVersion != 0 && info.Version < q.MinVersion:
+		return false
+	case q.MaxVersion != 0 && info.Version > q.MaxVersion:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+)
+
+// Expvar is a Recorder which publishes each series as a member of an
+// expvar.Map. Counters are published as numbers and histograms as objects
+// holding the count, sum, min and max of the values observed.
+type Expvar struct {
+	mu   sync.Mutex
+	vars *expvar.Map
+}
+
+// NewExpvar publishes a new map with the name provided. Like expvar.Publish,
+// NewExpvar panics if the name is already in use.
+func NewExpvar(name string) *Expvar {
+	return &Expvar{vars: expvar.NewMap(name)}
+}
+
+// Map returns the published map.
+func (e *Expvar) Map() *expvar.Map {
+	return e.vars
+}
+
+// Add implements the Recorder interface.
+func (e *Expvar) Add(name string, delta float64, labels ...Label) {
+	e.vars.AddFloat(key(name, labels), delta)
+}
+
+// Observe implements the Recorder interface.
+func (e *Expvar) Observe(name string, value float64, labels ...Label) {
+	k := key(name, labels)
+
+	e.mu.Lock()
+	h, ok := e.vars.Get(k).(*histogram)
+	if !ok {
+		h = &histogram{}
+		e.vars.Set(k, h)
+	}
+	e.mu.Unlock()
+
+	h.observe(value)
+}
+
+// histogram summarizes observations as an expvar.Var.
+type histogram struct {
+	mu    sync.Mutex
+	count uint64
+	sum   float64
+	min   float64
+	max   float64
+}
+
+func (h *histogram) observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.count == 0 || value < h.min {
+		h.min = value
+	}
+	if h.count == 0 || value > h.max {
+		h.max = value
+	}
+	h.count++
+	h.sum += value
+}
+
+// String implements the expvar.Var interface.
+func (h *histogram) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	b, _ := json.Marshal(struct {
+		Count uint64  `json:"count"`
+		Sum   float64 `json:"sum"`
+		Min   float64 `json:"min"`
+		Max   float64 `json:"max"`
+	}{h.count, h.sum, h.min, h.max})
+
+	return string(b)
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import "sync"
+
+// Memory is a Recorder which keeps every counter and observation in memory so
+// tests may assert against them.
+type Memory struct {
+	mu         sync.Mutex
+	counters   map[string]float64
+	histograms map[string][]float64
+}
+
+// NewMemory returns an empty *Memory.
+func NewMemory() *Memory {
+	return &Memory{
+		counters:   map[string]float64{},
+		histograms: map[string][]float64{},
+	}
+}
+
+// Add implements the Recorder interface.
+func (m *Memory) Add(name string, delta float64, labels ...Label) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.counters[key(name, labels)] += delta
+}
+
+// Observe implements the Recorder interface.
+func (m *Memory) Observe(name string, value float64, labels ...Label) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := key(name, labels)
+	m.histograms[k] = append(m.histograms[k], value)
+}
+
+// Counter returns the value of the counter with exactly the labels provided.
+func (m *Memory) Counter(name string, labels ...Label) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.counters[key(name, labels)]
+}
+
+// Histogram returns a copy of the values observed by the histogram with
+// exactly the labels provided.
+func (m *Memory) Histogram(name string, labels ...Label) []float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]float64(nil), m.histograms[key(name, labels)]...)
+}
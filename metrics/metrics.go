@@ -0,0 +1,108 @@
+// Package metrics defines the Recorder through which repositories and stores
+// report counters and histograms, along with an expvar backed Recorder and an
+// in-memory Recorder for tests.
+package metrics
+
+import (
+	"sort"
+	"strings"
+)
+
+// Names of the metrics recorded by the repository and the store decorator.
+const (
+	// ApplyTotal counts the commands applied.
+	ApplyTotal = "eventsource_apply_total"
+
+	// ApplyDuration observes the seconds taken to apply a command, from load
+	// to publish.
+	ApplyDuration = "eventsource_apply_duration_seconds"
+
+	// ApplyEvents observes the events produced by each successful command.
+	ApplyEvents = "eventsource_apply_events"
+
+	// LoadDuration observes the seconds taken to load and fold an aggregate.
+	LoadDuration = "eventsource_load_duration_seconds"
+
+	// LoadEvents observes the events folded by each load.
+	LoadEvents = "eventsource_load_events"
+
+	// StoreTotal counts the operations performed by a store.
+	StoreTotal = "eventsource_store_total"
+
+	// StoreDuration observes the seconds taken by each store operation.
+	StoreDuration = "eventsource_store_duration_seconds"
+)
+
+// Names of the labels attached to metrics.
+const (
+	LabelAggregateType = "aggregate_type"
+	LabelCommandType   = "command_type"
+	LabelOperation     = "operation"
+	LabelOutcome       = "outcome"
+)
+
+// Values of LabelOutcome.
+const (
+	OutcomeOK       = "ok"
+	OutcomeRejected = "rejected"
+	OutcomeNoEvents = "no_events"
+	OutcomeConflict = "conflict"
+	OutcomeNotFound = "not_found"
+	OutcomePanic    = "panic"
+	OutcomeError    = "error"
+)
+
+// Label is a name/value pair distinguishing series of the same metric.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Recorder receives counters and histogram observations. Implementations
+// must be safe for concurrent use.
+type Recorder interface {
+	// Add increments the counter by delta.
+	Add(name string, delta float64, labels ...Label)
+
+	// Observe records a value in the histogram.
+	Observe(name string, value float64, labels ...Label)
+}
+
+// Nop is a Recorder which discards everything.
+type Nop struct{}
+
+// Add implements the Recorder interface.
+func (Nop) Add(string, float64, ...Label) {}
+
+// Observe implements the Recorder interface.
+func (Nop) Observe(string, float64, ...Label) {}
+
+// key identifies a series by its name and labels sorted by label name, for
+// example `eventsource_apply_total{aggregate_type="Person",outcome="ok"}`.
+func key(name string, labels []Label) string {
+	if len(labels) == 0 {
+		return name
+	}
+
+	sorted := make([]Label, len(labels))
+	copy(sorted, labels)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('{')
+	for i, l := range sorted {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(l.Name)
+		b.WriteString(`="`)
+		b.WriteString(l.Value)
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
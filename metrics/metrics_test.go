@@ -0,0 +1,115 @@
+package metrics_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/metrics"
+	"github.com/aarongreenlee/eventsource/repository"
+	"github.com/aarongreenlee/eventsource/store/memory"
+	"github.com/aarongreenlee/eventsource/store/storetest"
+)
+
+func TestMemory(t *testing.T) {
+	m := metrics.NewMemory()
+	ok := metrics.Label{Name: metrics.LabelOutcome, Value: metrics.OutcomeOK}
+	typ := metrics.Label{Name: metrics.LabelAggregateType, Value: "Person"}
+
+	m.Add(metrics.ApplyTotal, 1, typ, ok)
+	m.Add(metrics.ApplyTotal, 2, ok, typ)
+	m.Observe(metrics.ApplyEvents, 3, typ)
+
+	assert.Equal(t, float64(3), m.Counter(metrics.ApplyTotal, ok, typ))
+	assert.Equal(t, float64(0), m.Counter(metrics.ApplyTotal, ok))
+	assert.Equal(t, []float64{3}, m.Histogram(metrics.ApplyEvents, typ))
+}
+
+func TestExpvar(t *testing.T) {
+	e := metrics.NewExpvar("eventsource_test")
+	ok := metrics.Label{Name: metrics.LabelOutcome, Value: metrics.OutcomeOK}
+
+	e.Add(metrics.ApplyTotal, 1, ok)
+	e.Add(metrics.ApplyTotal, 1, ok)
+	e.Observe(metrics.ApplyEvents, 1, ok)
+	e.Observe(metrics.ApplyEvents, 4, ok)
+
+	var published map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(e.Map().String()), &published))
+	assert.JSONEq(t, `2`, string(published[`eventsource_apply_total{outcome="ok"}`]))
+	assert.JSONEq(t, `{"count":2,"sum":5,"min":1,"max":4}`, string(published[`eventsource_apply_events{outcome="ok"}`]))
+}
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) es.Store {
+		return metrics.Store(memory.New(), metrics.Nop{})
+	})
+
+	ctx := context.Background()
+	m := metrics.NewMemory()
+	store := metrics.Store(memory.New(), m)
+
+	require.NoError(t, store.Save(ctx, "a", es.Record{Version: 1}))
+	_, err := store.Load(ctx, "missing", 0, 0)
+	require.True(t, errors.Is(err, es.ErrNotFound))
+
+	assert.Equal(t, float64(1), m.Counter(metrics.StoreTotal,
+		metrics.Label{Name: metrics.LabelOperation, Value: "save"},
+		metrics.Label{Name: metrics.LabelOutcome, Value: metrics.OutcomeOK},
+	))
+	assert.Equal(t, float64(1), m.Counter(metrics.StoreTotal,
+		metrics.Label{Name: metrics.LabelOperation, Value: "load"},
+		metrics.Label{Name: metrics.LabelOutcome, Value: metrics.OutcomeNotFound},
+	))
+}
+
+// Tally is the aggregate of the tests.
+type Tally struct {
+	Count int
+}
+
+func (t *Tally) On(es.Event) error {
+	t.Count++
+	return nil
+}
+
+func (t *Tally) Apply(_ context.Context, command es.Command) ([]es.Event, error) {
+	return []es.Event{&Counted{}}, nil
+}
+
+// Counted records that a Tally was counted.
+type Counted struct {
+	es.EventModel
+}
+
+func (Counted) EventType() string { return "counted" }
+
+// basicStore hides the optional interfaces of the memory store.
+type basicStore struct {
+	es.Store
+}
+
+// TestStoreCapabilities asserts the decorated store only implements the
+// optional interfaces of the underlying store.
+func TestStoreCapabilities(t *testing.T) {
+	store := metrics.Store(memory.New(), metrics.Nop{})
+	assert.Implements(t, (*es.TxStore)(nil), store)
+	assert.Implements(t, (*es.Lister)(nil), store)
+
+	store = metrics.Store(basicStore{Store: memory.New()}, metrics.Nop{})
+	_, ok := store.(es.TxStore)
+	assert.False(t, ok)
+	_, ok = store.(es.Lister)
+	assert.False(t, ok)
+
+	repo, err := repository.New(&Tally{}, []es.Event{&Counted{}}, repository.WithStore(store))
+	require.NoError(t, err)
+
+	_, err = repo.UnitOfWork().Add(es.CommandModel{ID: "a"}).Commit(context.Background())
+	assert.True(t, errors.Is(err, es.ErrTxUnsupported))
+}
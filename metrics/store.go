@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	es "github.com/aarongreenlee/eventsource"
+)
+
+// Store decorates an es.Store, recording StoreTotal and StoreDuration for each
+// operation. The decorated store implements es.Streamer, streaming through
+// es.Stream, and implements es.TxStore and es.Lister only when the underlying
+// store does.
+func Store(store es.Store, recorder Recorder) es.Store {
+	s := &instrumentedStore{store: store, recorder: recorder}
+
+	_, tx := store.(es.TxStore)
+	_, lister := store.(es.Lister)
+
+	switch {
+	case tx && lister:
+		return instrumentedTxLister{s}
+	case tx:
+		return instrumentedTxStore{s}
+	case lister:
+		return instrumentedLister{s}
+	default:
+		return s
+	}
+}
+
+// instrumentedStore implements the store returned by Store.
+type instrumentedStore struct {
+	store    es.Store
+	recorder Recorder
+}
+
+// Save implements the es.Store interface.
+func (s *instrumentedStore) Save(ctx context.Context, aggregateID string, records ...es.Record) error {
+	start := time.Now()
+	err := s.store.Save(ctx, aggregateID, records...)
+	s.record("save", start, err)
+	return err
+}
+
+// Load implements the es.Store interface.
+func (s *instrumentedStore) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int64) (es.History, error) {
+	start := time.Now()
+	history, err := s.store.Load(ctx, aggregateID, fromVersion, toVersion)
+	s.record("load", start, err)
+	return history, err
+}
+
+// Stream implements the es.Streamer interface. Only opening the cursor is
+// measured.
+func (s *instrumentedStore) Stream(ctx context.Context, aggregateID string, fromVersion, toVersion int64) (es.Cursor, error) {
+	start := time.Now()
+	cursor, err := es.Stream(ctx, s.store, aggregateID, fromVersion, toVersion)
+	s.record("stream", start, err)
+	return cursor, err
+}
+
+// saveAll measures SaveAll of a store implementing es.TxStore.
+func (s *instrumentedStore) saveAll(ctx context.Context, streams []es.StreamRecords) error {
+	start := time.Now()
+	err := s.store.(es.TxStore).SaveAll(ctx, streams...)
+	s.record("save_all", start, err)
+	return err
+}
+
+// list measures List of a store implementing es.Lister.
+func (s *instrumentedStore) list(ctx context.Context, query es.ListQuery) (es.ListPage, error) {
+	start := time.Now()
+	page, err := s.store.(es.Lister).List(ctx, query)
+	s.record("list", start, err)
+	return page, err
+}
+
+// instrumentedTxStore decorates a store implementing es.TxStore.
+type instrumentedTxStore struct {
+	*instrumentedStore
+}
+
+// SaveAll implements the es.TxStore interface.
+func (s instrumentedTxStore) SaveAll(ctx context.Context, streams ...es.StreamRecords) error {
+	return s.saveAll(ctx, streams)
+}
+
+// instrumentedLister decorates a store implementing es.Lister.
+type instrumentedLister struct {
+	*instrumentedStore
+}
+
+// List implements the es.Lister interface.
+func (s instrumentedLister) List(ctx context.Context, query es.ListQuery) (es.ListPage, error) {
+	return s.list(ctx, query)
+}
+
+// instrumentedTxLister decorates a store implementing es.TxStore and
+// es.Lister.
+type instrumentedTxLister struct {
+	*instrumentedStore
+}
+
+// SaveAll implements the es.TxStore interface.
+func (s instrumentedTxLister) SaveAll(ctx context.Context, streams ...es.StreamRecords) error {
+	return s.saveAll(ctx, streams)
+}
+
+// List implements the es.Lister interface.
+func (s instrumentedTxLister) List(ctx context.Context, query es.ListQuery) (es.ListPage, error) {
+	return s.list(ctx, query)
+}
+
+func (s *instrumentedStore) record(operation string, start time.Time, err error) {
+	labels := []Label{
+		{Name: LabelOperation, Value: operation},
+		{Name: LabelOutcome, Value: Outcome(err)},
+	}
+
+	s.recorder.Add(StoreTotal, 1, labels...)
+	s.recorder.Observe(StoreDuration, time.Since(start).Seconds(), labels...)
+}
+
+// Outcome classifies an error as a value of LabelOutcome.
+func Outcome(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeOK
+	case errors.Is(err, es.ErrConflict):
+		return OutcomeConflict
+	case errors.Is(err, es.ErrNotFound):
+		return OutcomeNotFound
+	case errors.Is(err, es.ErrNoEventsProduced):
+		return OutcomeNoEvents
+	default:
+		return OutcomeError
+	}
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"container/list"
+	"reflect"
+	"sync"
+
+	es "github.com/aarongreenlee/eventsource"
+)
+
+// Cloner may be implemented by aggregates which hold reference types such as
+// maps, slices or pointers. Cached aggregates are copied before they are
+// handed out; without Cloner only a shallow copy of the aggregate is made.
+type Cloner interface {
+	Clone() es.Aggregate
+}
+
+// CacheStats reports the effectiveness of the aggregate cache.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Len       int
+	Size      int
+}
+
+// cacheEntry is a folded aggregate and the version it was folded to.
+type cacheEntry struct {
+	aggregateID string
+	aggregate   es.Aggregate
+	version     int64
+}
+
+// cache is a least recently used cache of folded aggregates. A nil *cache is
+// valid and never holds an entry.
+type cache struct {
+	m         sync.Mutex
+	size      int
+	entries   *list.List
+	index     map[string]*list.Element
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+func newCache(size int) *cache {
+	return &cache{
+		size:    size,
+		entries: list.New(),
+		index:   make(map[string]*list.Element, size),
+	}
+}
+
+// get returns a copy of the cached aggregate and the version it was folded to.
+func (c *cache) get(aggregateID string) (es.Aggregate, int64, bool) {
+	if c == nil {
+		return nil, 0, false
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	element, ok := c.index[aggregateID]
+	if !ok {
+		c.misses++
+		return nil, 0, false
+	}
+
+	c.hits++
+	c.entries.MoveToFront(element)
+	entry := element.Value.(*cacheEntry)
+
+	return clone(entry.aggregate), entry.version, true
+}
+
+// put stores a copy of the aggregate folded to version. Older versions never
+// replace newer ones.
+func (c *cache) put(aggregateID string, aggregate es.Aggregate, version int64) {
+	if c == nil {
+		return
+	}
+
+	aggregate = clone(aggregate)
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if element, ok := c.index[aggregateID]; ok {
+		entry := element.Value.(*cacheEntry)
+		if entry.version <= version {
+			entry.aggregate = aggregate
+			entry.version = version
+		}
+		c.entries.MoveToFront(element)
+		return
+	}
+
+	c.index[aggregateID] = c.entries.PushFront(&cacheEntry{
+		aggregateID: aggregateID,
+		aggregate:   aggregate,
+		version:     version,
+	})
+
+	for c.entries.Len() > c.size {
+		oldest := c.entries.Back()
+		c.entries.Remove(oldest)
+		delete(c.index, oldest.Value.(*cacheEntry).aggregateID)
+		c.evictions++
+	}
+}
+
+// remove invalidates the cached aggregate.
+func (c *cache) remove(aggregateID string) {
+	if c == nil {
+		return
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if element, ok := c.index[aggregateID]; ok {
+		c.entries.Remove(element)
+		delete(c.index, aggregateID)
+	}
+}
+
+func (c *cache) stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return CacheStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Len:       c.entries.Len(),
+		Size:      c.size,
+	}
+}
+
+// clone copies the aggregate using Cloner when implemented or a shallow copy
+// otherwise.
+func clone(aggregate es.Aggregate) es.Aggregate {
+	if c, ok := aggregate.(Cloner); ok {
+		return c.Clone()
+	}
+
+	v := reflect.ValueOf(aggregate)
+	if v.Kind() != reflect.Ptr {
+		return aggregate
+	}
+
+	copied := reflect.New(v.Elem().Type())
+	copied.Elem().Set(v.Elem())
+
+	return copied.Interface().(es.Aggregate)
+}
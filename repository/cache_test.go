@@ -0,0 +1,67 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/repository"
+	"github.com/aarongreenlee/eventsource/store/memory"
+)
+
+// TestCache asserts cached aggregates are used, kept current with records
+// saved after they were cached and evicted once the cache is full.
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}},
+		repository.WithStore(store),
+		repository.WithCache(1),
+	)
+	require.NoError(t, err)
+
+	_, err = repo.Apply(ctx, increment("a", 1))
+	require.NoError(t, err)
+	_, err = repo.Apply(ctx, increment("a", 2))
+	require.NoError(t, err)
+
+	// The first command misses as the aggregate does not exist and the
+	// second misses before caching the folded aggregate.
+	stats := repo.CacheStats()
+	assert.Equal(t, uint64(0), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, 1, stats.Len)
+
+	// Records saved by another writer are folded into the cached aggregate.
+	other, err := repository.New(&Counter{}, []es.Event{&Incremented{}}, repository.WithStore(store))
+	require.NoError(t, err)
+	_, err = other.Apply(ctx, increment("a", 4))
+	require.NoError(t, err)
+
+	aggregate, err := repo.Load(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 7, aggregate.(*Counter).Value)
+	assert.Equal(t, int64(3), aggregate.(*Counter).Version)
+
+	// Mutating a loaded aggregate must not change the cached copy.
+	aggregate.(*Counter).Value = 100
+	aggregate, err = repo.Load(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 7, aggregate.(*Counter).Value)
+
+	stats = repo.CacheStats()
+	assert.Equal(t, uint64(2), stats.Hits)
+
+	_, err = repo.Apply(ctx, increment("b", 1))
+	require.NoError(t, err)
+	_, err = repo.Load(ctx, "b")
+	require.NoError(t, err)
+
+	stats = repo.CacheStats()
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.Equal(t, 1, stats.Len)
+}
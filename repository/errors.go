@@ -0,0 +1,273 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	es "github.com/aarongreenlee/eventsource"
+)
+
+const (
+	// ErrNotStarted indicates a command queued by WithCommandQueue was
+	// abandoned, because its context was done, before it started. The command
+	// has not been and will not be applied.
+	ErrNotStarted = es.Error("command was not started")
+
+	// ErrNilEvent indicates a nil event was produced.
+	ErrNilEvent = es.Error("event is nil")
+
+	// ErrAggregateMismatch indicates an event belongs to a different
+	// aggregate than the other events being saved.
+	ErrAggregateMismatch = es.Error("event belongs to a different aggregate")
+
+	// ErrVersionSequence indicates event versions do not continue from the
+	// current version of the aggregate without gaps.
+	ErrVersionSequence = es.Error("event version does not continue the stream")
+
+	// ErrEmptyEventType indicates an event has no type.
+	ErrEmptyEventType = es.Error("event type is empty")
+
+	// ErrZeroEventAt indicates an event has no timestamp.
+	ErrZeroEventAt = es.Error("event timestamp is zero")
+)
+
+// InvalidEventError is returned when an event violates the invariants of the
+// stream it is being saved to. Err holds the violation, such as
+// ErrVersionSequence, and may be tested with errors.Is.
+type InvalidEventError struct {
+	AggregateID string
+	Index       int
+	EventType   string
+	Version     int64
+	Expected    int64
+	Err         error
+}
+
+// Error implements the standard go Error interface.
+func (e *InvalidEventError) Error() string {
+	if e.Err == ErrVersionSequence {
+		return fmt.Sprintf("invalid event %d for aggregate %q: %s: expected version %d but found %d", e.Index, e.AggregateID, e.Err, e.Expected, e.Version)
+	}
+
+	return fmt.Sprintf("invalid event %d, %q, for aggregate %q: %s", e.Index, e.EventType, e.AggregateID, e.Err)
+}
+
+// Unwrap returns the violation.
+func (e *InvalidEventError) Unwrap() error {
+	return e.Err
+}
+
+// ExpectationError is returned when a command's es.Expectation is not met by
+// the aggregate it is applied to. Err is es.ErrAlreadyExists or
+// es.ErrNotFound and may be tested with errors.Is.
+type ExpectationError struct {
+	AggregateID string
+	CommandType string
+	Expected    es.Expectation
+	Version     int64
+	Err         error
+}
+
+// Error implements the standard go Error interface.
+func (e *ExpectationError) Error() string {
+	return fmt.Sprintf("command %q expected aggregate %q to be %s but found version %d: %s", e.CommandType, e.AggregateID, e.Expected, e.Version, e.Err)
+}
+
+// Unwrap returns es.ErrAlreadyExists or es.ErrNotFound.
+func (e *ExpectationError) Unwrap() error {
+	return e.Err
+}
+
+// expect returns an *ExpectationError when the aggregate, at version, does
+// not meet the expectation declared by the command.
+func expect(command es.Command, version int64) error {
+	e, ok := command.(es.Expecter)
+	if !ok {
+		return nil
+	}
+
+	var err error
+	switch expected := e.Expectation(); {
+	case expected == es.ExpectNew && version > 0:
+		err = es.ErrAlreadyExists
+	case expected == es.ExpectExists && version == 0:
+		err = es.ErrNotFound
+	default:
+		return nil
+	}
+
+	return &ExpectationError{
+		AggregateID: command.AggregateID(),
+		CommandType: command.EventType(),
+		Expected:    e.Expectation(),
+		Version:     version,
+		Err:         err,
+	}
+}
+
+// NotFoundError is returned when an aggregate has no history. It wraps
+// es.ErrNotFound so it may be tested with errors.Is.
+type NotFoundError struct {
+	AggregateID   string
+	AggregateType string
+}
+
+// Error implements the standard go Error interface.
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %q: %s", e.AggregateType, e.AggregateID, es.ErrNotFound)
+}
+
+// Unwrap returns es.ErrNotFound.
+func (e *NotFoundError) Unwrap() error {
+	return es.ErrNotFound
+}
+
+// isNotFound reports whether the aggregate has no history. Errors which merely
+// wrap es.ErrNotFound, such as a *FoldError whose On returned it, do not count.
+func isNotFound(err error) bool {
+	var notFound *NotFoundError
+	return errors.As(err, &notFound)
+}
+
+// DecodeError is returned when a stored record can not be unmarshaled into an
+// event, for example because its event type is no longer bound.
+type DecodeError struct {
+	AggregateID   string
+	AggregateType string
+	Version       int64
+	Err           error
+}
+
+// Error implements the standard go Error interface.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("unable to decode version %d of %s %q: %s", e.Version, e.AggregateType, e.AggregateID, e.Err)
+}
+
+// Unwrap returns the error of the serializer.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// FoldError is returned when the aggregate's On function fails to handle a
+// stored event. This is a programming error which may be solved by updating
+// the On function. Err holds the error returned by On or a *PanicError.
+type FoldError struct {
+	AggregateID   string
+	AggregateType string
+	EventType     string
+	Version       int64
+	Err           error
+}
+
+// Error implements the standard go Error interface.
+func (e *FoldError) Error() string {
+	return fmt.Sprintf("%s %q was unable to handle event %q, version %d: %s", e.AggregateType, e.AggregateID, e.EventType, e.Version, e.Err)
+}
+
+// Unwrap returns the error of the On function.
+func (e *FoldError) Unwrap() error {
+	return e.Err
+}
+
+// CommandRejectedError is returned when the command handler refuses a
+// command. Version is the version of the aggregate the command was applied
+// to and Err holds the error returned by the handler.
+type CommandRejectedError struct {
+	AggregateID   string
+	AggregateType string
+	CommandType   string
+	Version       int64
+	Err           error
+}
+
+// Error implements the standard go Error interface.
+func (e *CommandRejectedError) Error() string {
+	return fmt.Sprintf("command %q rejected by %s %q at version %d: %s", e.CommandType, e.AggregateType, e.AggregateID, e.Version, e.Err)
+}
+
+// Unwrap returns the error of the command handler.
+func (e *CommandRejectedError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is returned when the aggregate's On function, the command
+// handler or an observer panics. EventType and Version identify the event
+// being folded or observed; for a command handler Version is the version of
+// the aggregate the command was applied to.
+type PanicError struct {
+	AggregateID   string
+	AggregateType string
+	CommandType   string
+	EventType     string
+	Version       int64
+	Value         interface{}
+	Stack         []byte
+}
+
+// Error implements the standard go Error interface.
+func (e *PanicError) Error() string {
+	if e.CommandType != "" {
+		return fmt.Sprintf("panic applying command %q to %s %q at version %d: %v", e.CommandType, e.AggregateType, e.AggregateID, e.Version, e.Value)
+	}
+	return fmt.Sprintf("panic handling event %q, version %d, of %s %q: %v", e.EventType, e.Version, e.AggregateType, e.AggregateID, e.Value)
+}
+
+// Unwrap returns the panic value when it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// SelfCheckError is returned by New when WithSelfCheck finds events which do
+// not survive a round-trip through the serializer and into the aggregate.
+type SelfCheckError struct {
+	AggregateType string
+	Failures      []error
+}
+
+// Error implements the standard go Error interface.
+func (e *SelfCheckError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "self-check of %s failed for %d event(s)", e.AggregateType, len(e.Failures))
+	for _, failure := range e.Failures {
+		b.WriteString("\n\t")
+		b.WriteString(failure.Error())
+	}
+	return b.String()
+}
+
+// validate verifies the events all belong to the aggregate, continue from the
+// version provided without gaps and are typed and timestamped.
+func validate(aggregateID string, version int64, events []es.Event) error {
+	for i, event := range events {
+		if event == nil {
+			return &InvalidEventError{AggregateID: aggregateID, Index: i, Err: ErrNilEvent}
+		}
+
+		invalid := &InvalidEventError{
+			AggregateID: aggregateID,
+			Index:       i,
+			EventType:   event.EventType(),
+			Version:     event.EventVersion(),
+			Expected:    version + int64(i) + 1,
+		}
+
+		switch {
+		case event.AggregateID() != aggregateID:
+			invalid.Err = ErrAggregateMismatch
+		case invalid.Version != invalid.Expected:
+			invalid.Err = ErrVersionSequence
+		case invalid.EventType == "":
+			invalid.Err = ErrEmptyEventType
+		case event.EventAt().IsZero():
+			invalid.Err = ErrZeroEventAt
+		default:
+			continue
+		}
+
+		return invalid
+	}
+
+	return nil
+}
@@ -0,0 +1,327 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/repository"
+	"github.com/aarongreenlee/eventsource/serializer/gob"
+	"github.com/aarongreenlee/eventsource/store/memory"
+)
+
+// TestSaveValidatesEvents asserts events violating the invariants of a stream
+// are rejected before the store is touched.
+func TestSaveValidatesEvents(t *testing.T) {
+	now := time.Now()
+
+	testCases := map[string]struct {
+		events []es.Event
+		expect error
+	}{
+		"mixed aggregates": {
+			events: []es.Event{
+				&Incremented{ID: "a", Version: 1, At: now},
+				&Incremented{ID: "b", Version: 2, At: now},
+			},
+			expect: repository.ErrAggregateMismatch,
+		},
+		"version gap": {
+			events: []es.Event{
+				&Incremented{ID: "a", Version: 1, At: now},
+				&Incremented{ID: "a", Version: 3, At: now},
+			},
+			expect: repository.ErrVersionSequence,
+		},
+		"duplicate version": {
+			events: []es.Event{
+				&Incremented{ID: "a", Version: 1, At: now},
+				&Incremented{ID: "a", Version: 1, At: now},
+			},
+			expect: repository.ErrVersionSequence,
+		},
+		"zero timestamp": {
+			events: []es.Event{
+				&Incremented{ID: "a", Version: 1},
+			},
+			expect: repository.ErrZeroEventAt,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}})
+			require.NoError(t, err)
+
+			err = repo.Save(ctx, tc.events...)
+			assert.True(t, errors.Is(err, tc.expect), "expected %v but found %v", tc.expect, err)
+
+			var invalid *repository.InvalidEventError
+			assert.True(t, errors.As(err, &invalid))
+
+			_, err = repo.Store().Load(ctx, "a", 0, 0)
+			assert.True(t, errors.Is(err, es.ErrNotFound))
+		})
+	}
+}
+
+// TestApplyValidatesVersion asserts events produced by a command must
+// continue from the version loaded.
+func TestApplyValidatesVersion(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}})
+	require.NoError(t, err)
+
+	_, err = repo.Apply(ctx, increment("a", 1))
+	require.NoError(t, err)
+
+	cmd := increment("a", 1)
+	cmd.Version = 1
+	_, err = repo.Apply(ctx, cmd)
+
+	var invalid *repository.InvalidEventError
+	require.True(t, errors.As(err, &invalid), "expected an InvalidEventError but found %v", err)
+	assert.Equal(t, repository.ErrVersionSequence, invalid.Err)
+	assert.Equal(t, int64(2), invalid.Expected)
+	assert.Equal(t, int64(1), invalid.Version)
+}
+
+// Decremented is bound to the Counter repository but not handled by On.
+type Decremented struct {
+	Incremented
+}
+
+// EventType implements the eventsource.Event interface.
+func (e Decremented) EventType() string { return "decremented" }
+
+// Reset is bound to the Counter repository but not handled by On.
+type Reset struct {
+	Incremented
+}
+
+// EventType implements the eventsource.Event interface.
+func (e Reset) EventType() string { return "reset" }
+
+// TestSelfCheck asserts every bound event which can not be folded after a
+// round-trip through the serializer is reported.
+func TestSelfCheck(t *testing.T) {
+	_, err := repository.New(&Counter{}, []es.Event{&Incremented{}}, repository.WithSelfCheck())
+	require.NoError(t, err)
+
+	_, err = repository.New(&Counter{}, []es.Event{&Incremented{}, &Decremented{}, &Reset{}}, repository.WithSelfCheck())
+
+	var failed *repository.SelfCheckError
+	require.True(t, errors.As(err, &failed), "expected a SelfCheckError but found %v", err)
+	assert.Equal(t, "Counter", failed.AggregateType)
+	assert.Len(t, failed.Failures, 2)
+	assert.Contains(t, err.Error(), "decremented")
+	assert.Contains(t, err.Error(), "reset")
+}
+
+// pointerSerializer decodes Incremented as *Incremented regardless of the
+// form bound.
+type pointerSerializer struct {
+	es.Serializer
+}
+
+func (s pointerSerializer) UnmarshalEvent(record es.Record) (es.Event, error) {
+	event, err := s.Serializer.UnmarshalEvent(record)
+	if e, ok := event.(Incremented); ok {
+		return &e, err
+	}
+	return event, err
+}
+
+// TestSelfCheckConcreteType asserts an event decoded as a different concrete
+// type than the one bound is reported even when On handles it.
+func TestSelfCheckConcreteType(t *testing.T) {
+	serializer, err := gob.New()
+	require.NoError(t, err)
+
+	_, err = repository.New(&Counter{}, []es.Event{Incremented{}},
+		repository.WithSerializer(pointerSerializer{Serializer: serializer}),
+		repository.WithSelfCheck(),
+	)
+
+	var failed *repository.SelfCheckError
+	require.True(t, errors.As(err, &failed), "expected a SelfCheckError but found %v", err)
+	assert.Len(t, failed.Failures, 1)
+	assert.Contains(t, err.Error(), "*repository_test.Incremented")
+}
+
+// TestApplyExpectation asserts commands declaring an es.Expectation are
+// rejected when the aggregate does not meet it.
+func TestApplyExpectation(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}})
+	require.NoError(t, err)
+
+	cmd := increment("a", 1)
+	cmd.Expect = es.ExpectExists
+	_, err = repo.Apply(ctx, cmd)
+
+	var expectation *repository.ExpectationError
+	require.True(t, errors.As(err, &expectation), "expected an ExpectationError but found %v", err)
+	assert.True(t, errors.Is(err, es.ErrNotFound))
+	assert.Equal(t, es.ExpectExists, expectation.Expected)
+
+	cmd.Expect = es.ExpectNew
+	_, err = repo.Apply(ctx, cmd)
+	require.NoError(t, err)
+
+	_, err = repo.Apply(ctx, cmd)
+	require.True(t, errors.As(err, &expectation), "expected an ExpectationError but found %v", err)
+	assert.True(t, errors.Is(err, es.ErrAlreadyExists))
+	assert.Equal(t, int64(1), expectation.Version)
+
+	cmd.Expect = es.ExpectExists
+	version, err := repo.Apply(ctx, cmd)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), version)
+}
+
+// failingStore fails every Load with err.
+type failingStore struct {
+	es.Store
+	err error
+}
+
+func (s failingStore) Load(context.Context, string, int64, int64) (es.History, error) {
+	return nil, s.err
+}
+
+// TestApplyLoadError asserts a failure to load an aggregate is returned rather
+// than treated as a new aggregate.
+func TestApplyLoadError(t *testing.T) {
+	ctx := context.Background()
+	transient := errors.New("connection reset")
+	store := failingStore{Store: memory.New(), err: transient}
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}}, repository.WithStore(store))
+	require.NoError(t, err)
+
+	_, err = repo.Apply(ctx, increment("a", 1))
+	assert.True(t, errors.Is(err, transient), "expected %q but found %v", transient, err)
+
+	_, err = store.Store.Load(ctx, "a", 0, 0)
+	assert.True(t, errors.Is(err, es.ErrNotFound), "expected nothing to be saved but found %v", err)
+}
+
+// statusCode maps the errors of a repository onto HTTP status codes as a
+// service built on the repository might.
+func statusCode(err error) int {
+	var (
+		notFound    *repository.NotFoundError
+		expectation *repository.ExpectationError
+		rejected    *repository.CommandRejectedError
+	)
+
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.As(err, &expectation) && errors.Is(err, es.ErrAlreadyExists), errors.Is(err, es.ErrConflict):
+		return http.StatusConflict
+	case errors.As(err, &notFound), errors.As(err, &expectation):
+		return http.StatusNotFound
+	case errors.As(err, &rejected):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// TestTypedErrors asserts load and apply failures are reported as typed
+// errors carrying their cause.
+func TestTypedErrors(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}, &Decremented{}}, repository.WithStore(store))
+	require.NoError(t, err)
+
+	_, err = repo.Load(ctx, "a")
+	var notFound *repository.NotFoundError
+	require.True(t, errors.As(err, &notFound), "expected a NotFoundError but found %v", err)
+	assert.Equal(t, "Counter", notFound.AggregateType)
+	assert.True(t, errors.Is(err, es.ErrNotFound))
+	assert.Equal(t, http.StatusNotFound, statusCode(err))
+
+	_, err = repo.Apply(ctx, increment("a", -1))
+	var rejected *repository.CommandRejectedError
+	require.True(t, errors.As(err, &rejected), "expected a CommandRejectedError but found %v", err)
+	assert.Equal(t, "increment", rejected.CommandType)
+	assert.EqualError(t, rejected.Err, "counters may only be incremented")
+	assert.Equal(t, http.StatusUnprocessableEntity, statusCode(err))
+
+	require.NoError(t, repo.Save(ctx, &Decremented{Incremented{ID: "a", Version: 1, At: time.Now()}}))
+	_, err = repo.Load(ctx, "a")
+	var fold *repository.FoldError
+	require.True(t, errors.As(err, &fold), "expected a FoldError but found %v", err)
+	assert.Equal(t, "decremented", fold.EventType)
+	assert.Equal(t, int64(1), fold.Version)
+	assert.EqualError(t, fold.Err, "unhandled event *repository_test.Decremented")
+	assert.Equal(t, http.StatusInternalServerError, statusCode(err))
+
+	require.NoError(t, store.Save(ctx, "b", es.Record{Version: 1, Data: []byte("garbage")}))
+	_, err = repo.Load(ctx, "b")
+	var decode *repository.DecodeError
+	require.True(t, errors.As(err, &decode), "expected a DecodeError but found %v", err)
+	assert.Equal(t, int64(1), decode.Version)
+
+	cmd := increment("c", 1)
+	cmd.Expect = es.ExpectExists
+	_, err = repo.Apply(ctx, cmd)
+	assert.Equal(t, http.StatusNotFound, statusCode(err))
+}
+
+// Orphan is a Counter whose On function fails with an error wrapping
+// es.ErrNotFound, as when a referenced entity is missing.
+type Orphan struct {
+	Counter
+}
+
+// On implements the eventsource.Aggregate interface.
+func (o *Orphan) On(event es.Event) error {
+	if event.EventVersion() > 1 {
+		return fmt.Errorf("parent of %q: %w", event.AggregateID(), es.ErrNotFound)
+	}
+	return o.Counter.On(event)
+}
+
+// TestFoldNotFound asserts an aggregate whose On fails with an error wrapping
+// es.ErrNotFound is reported as corrupt rather than missing, so a command is
+// never applied to it as a new aggregate.
+func TestFoldNotFound(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := repository.New(&Orphan{}, []es.Event{&Incremented{}})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Save(ctx,
+		&Incremented{ID: "a", Version: 1, At: time.Now(), By: 1},
+		&Incremented{ID: "a", Version: 2, At: time.Now(), By: 1},
+	))
+
+	_, err = repo.Load(ctx, "a")
+	var fold *repository.FoldError
+	require.True(t, errors.As(err, &fold), "expected a FoldError but found %v", err)
+	assert.Equal(t, int64(2), fold.Version)
+	assert.False(t, errors.As(err, new(*repository.NotFoundError)))
+
+	_, err = repo.Apply(ctx, increment("a", 1))
+	require.True(t, errors.As(err, &fold), "expected a FoldError but found %v", err)
+
+	_, err = repo.UnitOfWork().Add(increment("a", 1)).Commit(ctx)
+	require.True(t, errors.As(err, &fold), "expected a FoldError but found %v", err)
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log entry.
+type Level int
+
+const (
+	// LevelDebug reports routine operations such as loads and saves.
+	LevelDebug Level = iota
+
+	// LevelInfo reports expected failures such as rejected commands.
+	LevelInfo
+
+	// LevelWarn reports failures which may succeed when retried such as
+	// conflicting saves.
+	LevelWarn
+
+	// LevelError reports failures which require attention.
+	LevelError
+)
+
+// String implements fmt.Stringer.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+}
+
+// Field is a key/value pair attached to a log entry.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Keys of the fields attached to the entries logged by the repository.
+const (
+	FieldAggregateID   = "aggregate_id"
+	FieldAggregateType = "aggregate_type"
+	FieldCommandType   = "command_type"
+	FieldDuration      = "duration"
+	FieldError         = "error"
+	FieldEvents        = "events"
+	FieldEventType     = "event_type"
+	FieldVersion       = "version"
+)
+
+// Logger receives structured, levelled entries from the repository.
+// Implementations must be safe for concurrent use.
+type Logger interface {
+	Log(ctx context.Context, level Level, msg string, fields ...Field)
+}
+
+// WithLogger logs loads, saves, conflicts, rejected commands and observer
+// failures to the logger provided.
+func WithLogger(logger Logger) Option {
+	return func(r *Repository) error {
+		if logger == nil {
+			return errors.New("must not provide a nil logger")
+		}
+		r.logger = logger
+		return nil
+	}
+}
+
+// nopLogger discards every entry.
+type nopLogger struct{}
+
+// Log implements the Logger interface.
+func (nopLogger) Log(context.Context, Level, string, ...Field) {}
+
+// writerLogger writes entries as lines of key=value pairs.
+type writerLogger struct {
+	mu  sync.Mutex
+	w   io.Writer
+	min Level
+}
+
+// NewWriterLogger returns a Logger which writes entries at or above min to w
+// as lines of key=value pairs.
+func NewWriterLogger(w io.Writer, min Level) Logger {
+	return &writerLogger{w: w, min: min}
+}
+
+// Log implements the Logger interface.
+func (l *writerLogger) Log(_ context.Context, level Level, msg string, fields ...Field) {
+	if level < l.min {
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "time=%s level=%s msg=%q", time.Now().UTC().Format(time.RFC3339Nano), level, msg)
+	for _, f := range fields {
+		fmt.Fprintf(&b, " %s=%s", f.Key, formatValue(f.Value))
+	}
+	b.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, _ = io.WriteString(l.w, b.String())
+}
+
+// formatValue quotes values which would otherwise be ambiguous.
+func formatValue(v interface{}) string {
+	s := fmt.Sprint(v)
+	if s == "" || strings.ContainsAny(s, " =\"\n\t") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
+
+// slogLogger adapts a *slog.Logger.
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger which writes entries to the *slog.Logger
+// provided with each Field as an attribute.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return slogLogger{logger: logger}
+}
+
+// Log implements the Logger interface.
+func (l slogLogger) Log(ctx context.Context, level Level, msg string, fields ...Field) {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		attrs = append(attrs, slog.Any(f.Key, f.Value))
+	}
+	l.logger.LogAttrs(ctx, slogLevel(level), msg, attrs...)
+}
+
+// slogLevel maps a Level onto the equivalent slog.Level.
+func slogLevel(level Level) slog.Level {
+	switch level {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelInfo:
+		return slog.LevelInfo
+	case LevelWarn:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
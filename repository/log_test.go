@@ -0,0 +1,187 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/repository"
+)
+
+// entry is a log entry captured by recordingLogger.
+type entry struct {
+	Level  repository.Level
+	Msg    string
+	Fields map[string]interface{}
+}
+
+// recordingLogger captures every entry logged.
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []entry
+}
+
+func (l *recordingLogger) Log(_ context.Context, level repository.Level, msg string, fields ...repository.Field) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := entry{Level: level, Msg: msg, Fields: map[string]interface{}{}}
+	for _, f := range fields {
+		e.Fields[f.Key] = f.Value
+	}
+	l.entries = append(l.entries, e)
+}
+
+// find returns the first entry with the message.
+func (l *recordingLogger) find(t *testing.T, msg string) entry {
+	t.Helper()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, e := range l.entries {
+		if e.Msg == msg {
+			return e
+		}
+	}
+
+	t.Fatalf("expected an entry %q but found %v", msg, l.entries)
+	return entry{}
+}
+
+// all returns every entry with the message.
+func (l *recordingLogger) all(msg string) []entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var found []entry
+	for _, e := range l.entries {
+		if e.Msg == msg {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// TestLogger asserts loads, saves, rejected commands and observer failures
+// are logged with their aggregate.
+func TestLogger(t *testing.T) {
+	ctx := context.Background()
+	logger := &recordingLogger{}
+	failure := errors.New("projection unavailable")
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}},
+		repository.WithLogger(logger),
+		repository.WithErrorObservers(func(context.Context, es.Event) error { return failure }),
+	)
+	require.NoError(t, err)
+
+	_, err = repo.Apply(ctx, increment("a", 1))
+	require.NoError(t, err)
+	_, err = repo.Apply(ctx, increment("a", -1))
+	require.Error(t, err)
+
+	saved := logger.find(t, "events saved")
+	assert.Equal(t, repository.LevelDebug, saved.Level)
+	assert.Equal(t, "a", saved.Fields[repository.FieldAggregateID])
+	assert.Equal(t, "Counter", saved.Fields[repository.FieldAggregateType])
+	assert.Equal(t, int64(1), saved.Fields[repository.FieldVersion])
+	assert.Contains(t, saved.Fields, repository.FieldDuration)
+
+	loaded := logger.find(t, "aggregate loaded")
+	assert.Equal(t, int64(1), loaded.Fields[repository.FieldVersion])
+
+	rejected := logger.find(t, "command rejected")
+	assert.Equal(t, repository.LevelInfo, rejected.Level)
+	assert.Equal(t, "increment", rejected.Fields[repository.FieldCommandType])
+
+	observer := logger.find(t, "observer failed")
+	assert.Equal(t, repository.LevelError, observer.Level)
+	assert.Equal(t, failure, observer.Fields[repository.FieldError])
+}
+
+func TestWriterLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := repository.NewWriterLogger(&buf, repository.LevelInfo)
+
+	logger.Log(context.Background(), repository.LevelDebug, "hidden")
+	logger.Log(context.Background(), repository.LevelWarn, "save conflict",
+		repository.Field{Key: repository.FieldAggregateID, Value: "a"},
+		repository.Field{Key: repository.FieldError, Value: es.ErrConflict},
+	)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `level=WARN msg="save conflict" aggregate_id=a error=conflict`)
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := repository.NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	logger.Log(context.Background(), repository.LevelDebug, "hidden")
+	logger.Log(context.Background(), repository.LevelWarn, "save conflict",
+		repository.Field{Key: repository.FieldVersion, Value: int64(3)},
+	)
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "save conflict", record["msg"])
+	assert.Equal(t, float64(3), record[repository.FieldVersion])
+}
+
+// failingSerializer fails to marshal every event.
+type failingSerializer struct {
+	es.Serializer
+}
+
+func (failingSerializer) MarshalEvent(es.Event) (es.Record, error) {
+	return es.Record{}, errors.New("disk full")
+}
+
+// TestLoggerUnsaved asserts invalid events and events which can not be
+// marshaled are logged by both Apply and UnitOfWork.Commit.
+func TestLoggerUnsaved(t *testing.T) {
+	ctx := context.Background()
+
+	invalid := increment("a", 1)
+	invalid.Version = 5
+
+	logger := &recordingLogger{}
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}}, repository.WithLogger(logger))
+	require.NoError(t, err)
+
+	_, err = repo.Apply(ctx, invalid)
+	require.Error(t, err)
+	_, err = repo.UnitOfWork().Add(invalid).Commit(ctx)
+	require.Error(t, err)
+
+	assert.Len(t, logger.all("invalid events"), 2)
+	failed := logger.find(t, "invalid events")
+	assert.Equal(t, repository.LevelError, failed.Level)
+	assert.Equal(t, "a", failed.Fields[repository.FieldAggregateID])
+
+	logger = &recordingLogger{}
+	repo, err = repository.New(&Counter{}, []es.Event{&Incremented{}},
+		repository.WithLogger(logger),
+		repository.WithSerializer(failingSerializer{Serializer: repo.Serializer()}),
+	)
+	require.NoError(t, err)
+
+	_, err = repo.Apply(ctx, increment("a", 1))
+	require.Error(t, err)
+	_, err = repo.UnitOfWork().Add(increment("a", 1)).Commit(ctx)
+	require.Error(t, err)
+
+	assert.Len(t, logger.all("unable to marshal events"), 2)
+}
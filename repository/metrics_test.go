@@ -0,0 +1,48 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/metrics"
+	"github.com/aarongreenlee/eventsource/repository"
+)
+
+// TestMetrics asserts applied commands are counted by outcome.
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	recorder := metrics.NewMemory()
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}}, repository.WithMetrics(recorder))
+	require.NoError(t, err)
+
+	_, err = repo.Apply(ctx, increment("a", 1))
+	require.NoError(t, err)
+	_, err = repo.Apply(ctx, increment("a", 0))
+	require.Error(t, err)
+	_, err = repo.Apply(ctx, increment("a", -1))
+	require.Error(t, err)
+
+	labels := func(outcome string) []metrics.Label {
+		return []metrics.Label{
+			{Name: metrics.LabelAggregateType, Value: "Counter"},
+			{Name: metrics.LabelCommandType, Value: "increment"},
+			{Name: metrics.LabelOutcome, Value: outcome},
+		}
+	}
+
+	assert.Equal(t, float64(1), recorder.Counter(metrics.ApplyTotal, labels(metrics.OutcomeOK)...))
+	assert.Equal(t, float64(1), recorder.Counter(metrics.ApplyTotal, labels(metrics.OutcomeNoEvents)...))
+	assert.Equal(t, float64(1), recorder.Counter(metrics.ApplyTotal, labels(metrics.OutcomeRejected)...))
+	assert.Equal(t, []float64{1}, recorder.Histogram(metrics.ApplyEvents, labels(metrics.OutcomeOK)...))
+
+	loads := recorder.Histogram(metrics.LoadEvents,
+		metrics.Label{Name: metrics.LabelAggregateType, Value: "Counter"},
+		metrics.Label{Name: metrics.LabelOutcome, Value: metrics.OutcomeOK},
+	)
+	assert.Equal(t, []float64{1, 1}, loads)
+}
@@ -0,0 +1,106 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/metrics"
+	"github.com/aarongreenlee/eventsource/repository"
+)
+
+// Fragile is a Counter whose On function panics, writing to a nil map, once
+// its value reaches 1.
+type Fragile struct {
+	Counter
+	limits map[string]int
+}
+
+// On implements the eventsource.Aggregate interface.
+func (f *Fragile) On(event es.Event) error {
+	if f.Value >= 1 {
+		f.limits["value"]++
+	}
+	return f.Counter.On(event)
+}
+
+// TestPanicRecovered asserts panics raised while folding, handling commands
+// and notifying observers are returned or logged as a *PanicError.
+func TestPanicRecovered(t *testing.T) {
+	ctx := context.Background()
+	logger := &recordingLogger{}
+
+	repo, err := repository.New(&Fragile{}, []es.Event{&Incremented{}},
+		repository.WithLogger(logger),
+		repository.WithObservers(func(es.Event) { panic("observer") }),
+	)
+	require.NoError(t, err)
+
+	_, err = repo.Apply(ctx, increment("a", 1))
+	require.NoError(t, err)
+
+	observer := logger.find(t, "observer failed")
+	var panicErr *repository.PanicError
+	require.True(t, errors.As(observer.Fields[repository.FieldError].(error), &panicErr))
+	assert.Equal(t, "observer", panicErr.Value)
+
+	_, err = repo.Apply(ctx, increment("a", 1))
+	require.NoError(t, err)
+
+	_, err = repo.Load(ctx, "a")
+	require.True(t, errors.As(err, &panicErr), "expected a PanicError but found %v", err)
+	assert.Equal(t, "a", panicErr.AggregateID)
+	assert.Equal(t, "incremented", panicErr.EventType)
+	assert.Equal(t, int64(2), panicErr.Version)
+	assert.Contains(t, string(panicErr.Stack), "(*Fragile).On")
+	assert.True(t, errors.As(err, new(interface{ RuntimeError() })), "expected the runtime error to be unwrapped")
+}
+
+// TestPanicHandler asserts a panicking command handler is returned as a
+// *PanicError, counted with the panic outcome, and nothing is saved.
+func TestPanicHandler(t *testing.T) {
+	ctx := context.Background()
+	recorder := metrics.NewMemory()
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}},
+		repository.WithHandler(func(context.Context, es.Aggregate, es.Command) ([]es.Event, error) {
+			panic("handler")
+		}),
+		repository.WithMetrics(recorder),
+	)
+	require.NoError(t, err)
+
+	_, err = repo.Apply(ctx, increment("a", 1))
+
+	var panicErr *repository.PanicError
+	require.True(t, errors.As(err, &panicErr), "expected a PanicError but found %v", err)
+	assert.Equal(t, "increment", panicErr.CommandType)
+	assert.Equal(t, int64(0), panicErr.Version)
+	assert.Equal(t, float64(1), recorder.Counter(metrics.ApplyTotal,
+		metrics.Label{Name: metrics.LabelAggregateType, Value: "Counter"},
+		metrics.Label{Name: metrics.LabelCommandType, Value: "increment"},
+		metrics.Label{Name: metrics.LabelOutcome, Value: metrics.OutcomePanic},
+	))
+
+	_, err = repo.Load(ctx, "a")
+	assert.True(t, errors.Is(err, es.ErrNotFound))
+}
+
+// TestPanicStrict asserts WithStrictPanics lets panics propagate.
+func TestPanicStrict(t *testing.T) {
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}},
+		repository.WithStrictPanics(),
+		repository.WithHandler(func(context.Context, es.Aggregate, es.Command) ([]es.Event, error) {
+			panic("handler")
+		}),
+	)
+	require.NoError(t, err)
+
+	assert.PanicsWithValue(t, "handler", func() {
+		_, _ = repo.Apply(context.Background(), increment("a", 1))
+	})
+}
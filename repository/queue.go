@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	es "github.com/aarongreenlee/eventsource"
+)
+
+// QueueStats describes the command queue.
+type QueueStats struct {
+	// Aggregates is the number of aggregates with a goroutine serving their
+	// commands.
+	Aggregates int
+
+	// Queued is the number of commands waiting across every aggregate.
+	Queued int
+}
+
+// queues serves the commands of each aggregate from a goroutine of its own. A
+// nil *queues is valid and reports empty stats.
+type queues struct {
+	mu     sync.Mutex
+	depth  int
+	idle   time.Duration
+	actors map[string]chan *job
+}
+
+// States of a job.
+const (
+	jobQueued int32 = iota
+	jobStarted
+	jobCancelled
+)
+
+// job is a command waiting to be applied. Its state moves from jobQueued to
+// either jobStarted, by the goroutine serving it, or jobCancelled, by the
+// caller, exactly once.
+type job struct {
+	ctx     context.Context
+	fn      func() (int64, error)
+	state   atomic.Int32
+	version int64
+	err     error
+	done    chan struct{}
+}
+
+func newQueues(depth int, idle time.Duration) *queues {
+	return &queues{
+		depth:  depth,
+		idle:   idle,
+		actors: map[string]chan *job{},
+	}
+}
+
+// do queues fn behind the commands already waiting for the aggregate and
+// waits for it to run. If ctx is done before fn starts, fn is never run and an
+// error wrapping ErrNotStarted and the context's error is returned. Once fn
+// has started do waits for it and returns its result.
+func (q *queues) do(ctx context.Context, aggregateID string, fn func() (int64, error)) (int64, error) {
+	j := &job{ctx: ctx, fn: fn, done: make(chan struct{})}
+
+	// Jobs are queued while holding the lock so an actor can not exit between
+	// being found and being handed the job.
+	q.mu.Lock()
+	jobs, ok := q.actors[aggregateID]
+	if !ok {
+		jobs = make(chan *job, q.depth)
+		q.actors[aggregateID] = jobs
+		go q.serve(aggregateID, jobs)
+	}
+
+	select {
+	case jobs <- j:
+	default:
+		q.mu.Unlock()
+		return 0, fmt.Errorf("%w: %d commands are waiting for aggregate %q", es.ErrBusy, q.depth, aggregateID)
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		if j.state.CompareAndSwap(jobQueued, jobCancelled) {
+			return 0, fmt.Errorf("%w: %w", ErrNotStarted, ctx.Err())
+		}
+		<-j.done
+	}
+
+	return j.version, j.err
+}
+
+// serve runs the jobs of the aggregate until it has been idle for q.idle.
+func (q *queues) serve(aggregateID string, jobs chan *job) {
+	timer := time.NewTimer(q.idle)
+	defer timer.Stop()
+
+	for {
+		select {
+		case j := <-jobs:
+			// A job cancelled while queued has already been abandoned by
+			// its caller.
+			if j.state.CompareAndSwap(jobQueued, jobStarted) {
+				if err := j.ctx.Err(); err != nil {
+					j.err = fmt.Errorf("%w: %w", ErrNotStarted, err)
+				} else {
+					j.version, j.err = j.fn()
+				}
+				close(j.done)
+			}
+
+		case <-timer.C:
+			q.mu.Lock()
+			if len(jobs) == 0 {
+				delete(q.actors, aggregateID)
+				q.mu.Unlock()
+				return
+			}
+			q.mu.Unlock()
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(q.idle)
+	}
+}
+
+func (q *queues) stats() QueueStats {
+	if q == nil {
+		return QueueStats{}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s := QueueStats{Aggregates: len(q.actors)}
+	for _, jobs := range q.actors {
+		s.Queued += len(jobs)
+	}
+	return s
+}
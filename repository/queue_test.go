@@ -0,0 +1,161 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/repository"
+	"github.com/aarongreenlee/eventsource/store/memory"
+)
+
+// TestCommandQueue asserts concurrent commands to one aggregate are applied
+// one at a time without conflicting.
+func TestCommandQueue(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}},
+		repository.WithCommandQueue(100, time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			_, err := repo.Apply(ctx, increment(id, 1))
+			assert.NoError(t, err)
+		}([]string{"a", "b"}[i%2])
+	}
+	wg.Wait()
+
+	for _, id := range []string{"a", "b"} {
+		aggregate, err := repo.Load(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, 25, aggregate.(*Counter).Value)
+		assert.Equal(t, int64(25), aggregate.(*Counter).Version)
+	}
+
+	require.Eventually(t, func() bool {
+		return repo.QueueStats() == repository.QueueStats{}
+	}, time.Second, time.Millisecond, "expected idle aggregates to be evicted")
+}
+
+// TestCommandQueueBusy asserts commands beyond the queue depth fail with
+// es.ErrBusy.
+func TestCommandQueueBusy(t *testing.T) {
+	ctx := context.Background()
+	started, release := make(chan struct{}), make(chan struct{})
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}},
+		repository.WithCommandQueue(1, time.Minute),
+		repository.WithHandler(func(ctx context.Context, aggregate es.Aggregate, command es.Command) ([]es.Event, error) {
+			started <- struct{}{}
+			<-release
+			return aggregate.(*Counter).Apply(ctx, command)
+		}),
+	)
+	require.NoError(t, err)
+
+	results := make(chan error, 2)
+	go func() {
+		_, err := repo.Apply(ctx, increment("a", 1))
+		results <- err
+	}()
+	<-started
+
+	go func() {
+		_, err := repo.Apply(ctx, increment("a", 1))
+		results <- err
+	}()
+	require.Eventually(t, func() bool {
+		return repo.QueueStats().Queued == 1
+	}, time.Second, time.Millisecond)
+
+	_, err = repo.Apply(ctx, increment("a", 1))
+	assert.True(t, errors.Is(err, es.ErrBusy), "expected %q but found %v", es.ErrBusy, err)
+
+	close(release)
+	<-started
+	require.NoError(t, <-results)
+	require.NoError(t, <-results)
+
+	aggregate, err := repo.Load(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), aggregate.(*Counter).Version)
+}
+
+// detachedStore saves regardless of the caller's context.
+type detachedStore struct {
+	es.Store
+}
+
+func (s detachedStore) Save(_ context.Context, aggregateID string, records ...es.Record) error {
+	return s.Store.Save(context.Background(), aggregateID, records...)
+}
+
+// TestCommandQueueCancel asserts a command abandoned while queued is never
+// applied and a command cancelled after it started returns its result.
+func TestCommandQueueCancel(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}},
+		repository.WithStore(detachedStore{Store: memory.New()}),
+		repository.WithCommandQueue(10, time.Minute),
+		repository.WithHandler(func(ctx context.Context, aggregate es.Aggregate, command es.Command) ([]es.Event, error) {
+			started <- struct{}{}
+			<-release
+			return aggregate.(*Counter).Apply(ctx, command)
+		}),
+	)
+	require.NoError(t, err)
+
+	running, cancelRunning := context.WithCancel(context.Background())
+	results := make(chan *repository.Result, 1)
+	go func() {
+		result, err := repo.ApplyResult(running, increment("a", 1))
+		assert.NoError(t, err)
+		results <- result
+	}()
+	<-started
+
+	queued, cancelQueued := context.WithCancel(context.Background())
+	abandoned := make(chan error, 1)
+	go func() {
+		_, err := repo.Apply(queued, increment("a", 1))
+		abandoned <- err
+	}()
+	require.Eventually(t, func() bool {
+		return repo.QueueStats().Queued == 1
+	}, time.Second, time.Millisecond)
+
+	cancelQueued()
+	err = <-abandoned
+	assert.True(t, errors.Is(err, repository.ErrNotStarted), "expected %q but found %v", repository.ErrNotStarted, err)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	// The handler has started, so cancelling its context waits for the
+	// command and returns what it saved.
+	cancelRunning()
+	select {
+	case <-results:
+		t.Fatal("expected ApplyResult to wait for the running command")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+
+	result := <-results
+	require.NotNil(t, result)
+	assert.Equal(t, int64(1), result.Version)
+
+	aggregate, err := repo.Load(context.Background(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), aggregate.(*Counter).Version)
+}
@@ -4,26 +4,45 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"reflect"
+	"runtime/debug"
 	"strings"
 	"time"
 
 	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/catalog"
+	"github.com/aarongreenlee/eventsource/metrics"
 	"github.com/aarongreenlee/eventsource/serializer/gob"
 	"github.com/aarongreenlee/eventsource/store/memory"
+	"github.com/aarongreenlee/eventsource/trace"
 )
 
 // Repository provides the primary abstraction to saving and loading events
 type Repository struct {
-	debug      bool
-	observers  []func(es.Event)
-	prototype  reflect.Type
-	serializer es.Serializer
-	store      es.Store
-	writer     io.Writer
+	aggregateType string
+	cache         *cache
+	factory       func() es.Aggregate
+	handler       HandlerFunc
+	logger        Logger
+	metrics       metrics.Recorder
+	tracer        trace.Tracer
+	observers     []ObserverFunc
+	queues        *queues
+	selfCheck     bool
+	serializer    es.Serializer
+	stamping      bool
+	store         es.Store
+	strictPanics  bool
 }
 
+// HandlerFunc applies a command to an aggregate to generate a new set of
+// events.
+type HandlerFunc func(ctx context.Context, aggregate es.Aggregate, command es.Command) ([]es.Event, error)
+
+// ObserverFunc is notified of each saved event. An error returned by an
+// observer is logged; it does not fail the command which produced the event.
+type ObserverFunc func(ctx context.Context, event es.Event) error
+
 // Option provides functional configuration for a *Repository
 type Option func(*Repository) error
 
@@ -59,16 +78,175 @@ func WithEvents(events ...es.Event) Option {
 	}
 }
 
+// WithAggregateType names the aggregate type managed by the repository. The
+// name is stored with every record, filters es.ListQuery and labels logs,
+// metrics and spans. It defaults to the Go type name of the aggregate without
+// its package, so aggregates with the same name in different packages sharing
+// a store must be given distinct names. Provide this option before
+// WithCatalog.
+func WithAggregateType(name string) Option {
+	return func(r *Repository) error {
+		if name == "" {
+			return errors.New("must not provide a blank aggregate type")
+		}
+		r.aggregateType = name
+		return nil
+	}
+}
+
+// WithCatalog registers the events bound to the repository with the catalog
+// as emitted by the repository's aggregate type. If you choose not to use the
+// default serializer, or name the aggregate type, you need to provide the
+// WithSerializer and WithAggregateType options before this option.
+func WithCatalog(c *catalog.Catalog) Option {
+	return func(r *Repository) error {
+		if c == nil {
+			return errors.New("must not provide a nil catalog")
+		}
+		if r.serializer == nil {
+			return errors.New("a serializer must have been configured for the repository before adding a catalog")
+		}
+		r.serializer = c.Serializer(r.aggregateType, r.serializer)
+		return nil
+	}
+}
+
 // WithObservers allows observers to watch the saved events; Observers should
 // invoke very short lived operations as calls will block until the observer is
 // finished
 func WithObservers(observers ...func(event es.Event)) Option {
+	return func(r *Repository) error {
+		for _, observer := range observers {
+			observer := observer
+			r.observers = append(r.observers, func(_ context.Context, event es.Event) error {
+				observer(event)
+				return nil
+			})
+		}
+		return nil
+	}
+}
+
+// WithErrorObservers allows observers which may fail to watch the saved
+// events. Failures are reported to the Logger configured by WithLogger.
+func WithErrorObservers(observers ...ObserverFunc) Option {
 	return func(r *Repository) error {
 		r.observers = append(r.observers, observers...)
 		return nil
 	}
 }
 
+// WithMetrics records the duration and outcome of loads and applied commands
+// with the recorder provided. Use metrics.Store to also measure the store.
+func WithMetrics(recorder metrics.Recorder) Option {
+	return func(r *Repository) error {
+		if recorder == nil {
+			return errors.New("must not provide a nil metrics recorder")
+		}
+		r.metrics = recorder
+		return nil
+	}
+}
+
+// WithTracer traces applied commands, loads, folds, serialization and saves as
+// children of the span carried by the context. Use trace.Store and
+// trace.Serializer to also trace within the store and serializer.
+func WithTracer(tracer trace.Tracer) Option {
+	return func(r *Repository) error {
+		if tracer == nil {
+			return errors.New("must not provide a nil tracer")
+		}
+		r.tracer = tracer
+		return nil
+	}
+}
+
+// WithCommandQueue applies the commands of each aggregate one at a time, in
+// the order they arrive, while commands to different aggregates run in
+// parallel. Up to depth commands may wait for each aggregate; further commands
+// fail with es.ErrBusy. The goroutine serving an aggregate exits once it has
+// been idle for the duration provided.
+//
+// A command whose context is done while it waits is abandoned and fails with
+// ErrNotStarted; once a command has started its result is always returned.
+//
+// Commands are only ordered within the process; the store still guards
+// against writers in other processes with es.ErrConflict.
+func WithCommandQueue(depth int, idle time.Duration) Option {
+	return func(r *Repository) error {
+		if depth < 1 {
+			return errors.New("command queue depth must be greater than 0")
+		}
+		if idle <= 0 {
+			return errors.New("command queue idle timeout must be greater than 0")
+		}
+		r.queues = newQueues(depth, idle)
+		return nil
+	}
+}
+
+// WithStrictPanics lets panics raised by the aggregate's On function, the
+// command handler and observers propagate rather than being recovered as a
+// *PanicError. Tests may use it so a panic fails loudly.
+func WithStrictPanics() Option {
+	return func(r *Repository) error {
+		r.strictPanics = true
+		return nil
+	}
+}
+
+// WithSelfCheck verifies each event bound by New can be marshaled,
+// unmarshaled to the same event type and folded by the On function of a new
+// aggregate. The events provided to New are expected to be zero values. New
+// fails with a *SelfCheckError listing every mismatch found.
+func WithSelfCheck() Option {
+	return func(r *Repository) error {
+		r.selfCheck = true
+		return nil
+	}
+}
+
+// WithCache keeps up to size folded aggregates in memory. Cached aggregates
+// are brought up to date by folding only the records saved after the cached
+// version so loads of frequently used aggregates avoid re-reading their full
+// history.
+func WithCache(size int) Option {
+	return func(r *Repository) error {
+		if size < 1 {
+			return errors.New("cache size must be greater than 0")
+		}
+		r.cache = newCache(size)
+		return nil
+	}
+}
+
+// WithHandler routes commands to the handler provided instead of the
+// es.CommandHandler implemented by the aggregate. Only one handler may be
+// provided.
+func WithHandler(handler HandlerFunc) Option {
+	return func(r *Repository) error {
+		if handler == nil {
+			return errors.New("must not provide a nil handler")
+		}
+		if r.handler != nil {
+			return errors.New("a handler has already been configured for the repository")
+		}
+		r.handler = handler
+		return nil
+	}
+}
+
+// WithStamping configures the repository to assign the aggregate id, next
+// version and, when not already set, the timestamp of each event produced by
+// a command. Only events implementing es.Stamper, such as pointers to events
+// embedding es.EventModel, are stamped.
+func WithStamping() Option {
+	return func(r *Repository) error {
+		r.stamping = true
+		return nil
+	}
+}
+
 // New creates a new Repository using defaults and then applying any
 // operations.
 //
@@ -78,13 +256,53 @@ func WithObservers(observers ...func(event es.Event)) Option {
 //
 //	* Memory store
 //	* Gob serializer
+//
 func New(prototype es.Aggregate, events []es.Event, opts ...Option) (*Repository, error) {
+	if prototype == nil {
+		return nil, errors.New("must not provide a nil prototype")
+	}
+
 	t := reflect.TypeOf(prototype)
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
 
-	r := &Repository{prototype: t}
+	factory := func() es.Aggregate {
+		return reflect.New(t).Interface().(es.Aggregate)
+	}
+
+	return NewWithFactory(factory, events, opts...)
+}
+
+// NewFor creates a new Repository for aggregates of type A without
+// reflection; each new aggregate is built with new(A). The pointer type, *A,
+// must implement es.Aggregate, which is checked at compile time. The defaults
+// are the same as New.
+func NewFor[A any, P interface {
+	*A
+	es.Aggregate
+}](events []es.Event, opts ...Option) (*Repository, error) {
+	factory := func() es.Aggregate {
+		return P(new(A))
+	}
+
+	return NewWithFactory(factory, events, opts...)
+}
+
+// NewWithFactory creates a new Repository which builds new instances of the
+// aggregate by calling factory. The defaults are the same as New.
+func NewWithFactory(factory func() es.Aggregate, events []es.Event, opts ...Option) (*Repository, error) {
+	if factory == nil {
+		return nil, errors.New("must not provide a nil aggregate factory")
+	}
+
+	r := &Repository{
+		aggregateType: aggregateTypeOf(factory()),
+		factory:       factory,
+		logger:        nopLogger{},
+		metrics:       metrics.Nop{},
+		tracer:        trace.Nop{},
+	}
 
 	defaultSerializer, err := gob.New()
 	if err != nil {
@@ -114,48 +332,187 @@ func New(prototype es.Aggregate, events []es.Event, opts ...Option) (*Repository
 		return nil, err
 	}
 
+	if r.selfCheck {
+		if err := r.check(events); err != nil {
+			return nil, err
+		}
+	}
+
 	return r, nil
 }
 
-func (r *Repository) logf(format string, args ...interface{}) {
-	if !r.debug {
-		return
+// check round-trips each event through the serializer and into the On
+// function of a new aggregate.
+func (r *Repository) check(events []es.Event) error {
+	var failures []error
+
+	for _, event := range events {
+		if event == nil {
+			failures = append(failures, ErrNilEvent)
+			continue
+		}
+
+		eventType := event.EventType()
+
+		record, err := r.serializer.MarshalEvent(event)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("event %q, %T, could not be marshaled: %w", eventType, event, err))
+			continue
+		}
+
+		decoded, err := r.serializer.UnmarshalEvent(record)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("event %q, %T, could not be unmarshaled: %w", eventType, event, err))
+			continue
+		}
+
+		// Matching the concrete type also catches a value bound where On
+		// expects a pointer, or the reverse.
+		if decoded.EventType() != eventType || reflect.TypeOf(decoded) != reflect.TypeOf(event) {
+			failures = append(failures, fmt.Errorf("event %q, %T, was unmarshaled as %q, %T", eventType, event, decoded.EventType(), decoded))
+			continue
+		}
+
+		if err := r.New().On(decoded); err != nil {
+			failures = append(failures, fmt.Errorf("event %q, %T, unmarshaled as %T, was not handled by %s: %w", eventType, event, decoded, r.aggregateType, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return &SelfCheckError{AggregateType: r.aggregateType, Failures: failures}
 	}
 
-	now := time.Now().UTC().Format(time.StampMilli)
-	_, _ = io.WriteString(r.writer, now)
-	_, _ = io.WriteString(r.writer, " ")
+	return nil
+}
 
-	_, _ = fmt.Fprintf(r.writer, format, args...)
-	if !strings.HasSuffix(format, "\n") {
-		_, _ = io.WriteString(r.writer, "\n")
+// aggregateTypeOf names the type of the aggregate without its package or
+// pointer prefix. It is the default of WithAggregateType.
+func aggregateTypeOf(aggregate es.Aggregate) string {
+	name := fmt.Sprintf("%T", aggregate)
+	name = strings.TrimLeft(name, "*")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
 	}
+	return name
 }
 
 // New returns a new instance of the aggregate
 func (r *Repository) New() es.Aggregate {
-	return reflect.New(r.prototype).Interface().(es.Aggregate)
+	return r.factory()
 }
 
-// Save persists the events into the underlying Store
+// AggregateType returns the name of the aggregate type managed by the
+// repository. See WithAggregateType.
+func (r *Repository) AggregateType() string {
+	return r.aggregateType
+}
+
+// Save persists the events into the underlying Store. The events must belong
+// to a single aggregate and have contiguous versions; an *InvalidEventError is
+// returned otherwise and nothing is saved.
+//
+// Save does not load the aggregate, so the first event's version is trusted to
+// follow the stored version. The Store rejects events which do not: the
+// bundled stores report an overlap with the stored stream as es.ErrConflict
+// and a gap as es.ErrVersionGap.
 func (r *Repository) Save(ctx context.Context, events ...es.Event) error {
 	if len(events) == 0 {
 		return nil
 	}
 
+	if events[0] == nil {
+		return &InvalidEventError{Err: ErrNilEvent}
+	}
+
 	aggregateID := events[0].AggregateID()
 
+	if err := validate(aggregateID, events[0].EventVersion()-1, events); err != nil {
+		return err
+	}
+
+	_, err := r.save(ctx, aggregateID, events)
+	return err
+}
+
+// save marshals and persists events which have been validated.
+func (r *Repository) save(ctx context.Context, aggregateID string, events []es.Event) (es.History, error) {
+	ctx, span := r.startSpan(ctx, trace.SpanSave, aggregateID)
+	defer span.End()
+
+	history, err := r.marshal(ctx, events)
+	if err != nil {
+		span.RecordError(err)
+		r.logUnsaved(ctx, "unable to marshal events", aggregateID, events, err)
+		return nil, err
+	}
+
+	start := time.Now()
+	err = r.store.Save(ctx, aggregateID, history...)
+	r.logSave(ctx, aggregateID, events, start, err)
+	if err != nil {
+		span.RecordError(err)
+
+		// The cached aggregate may no longer reflect the store, for example
+		// after an es.ErrConflict, so it must be folded again.
+		r.cache.remove(aggregateID)
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// marshal serializes the events into records.
+func (r *Repository) marshal(ctx context.Context, events []es.Event) (es.History, error) {
+	ctx, span := r.tracer.Start(ctx, trace.SpanMarshal,
+		trace.Attribute{Key: trace.AttrAggregateType, Value: r.aggregateType},
+		trace.Attribute{Key: trace.AttrEvents, Value: len(events)},
+	)
+	defer span.End()
+
 	history := make(es.History, 0, len(events))
 	for _, event := range events {
-		record, err := r.serializer.MarshalEvent(event)
+		record, err := es.MarshalEvent(ctx, r.serializer, event)
 		if err != nil {
-			return err
+			span.RecordError(err)
+			return nil, err
 		}
 
+		record.AggregateType = r.aggregateType
 		history = append(history, record)
 	}
 
-	return r.store.Save(ctx, aggregateID, history...)
+	return history, nil
+}
+
+// logUnsaved logs a failure which prevented events from being saved before
+// the store was called.
+func (r *Repository) logUnsaved(ctx context.Context, msg, aggregateID string, events []es.Event, err error) {
+	r.logger.Log(ctx, LevelError, msg,
+		Field{Key: FieldAggregateID, Value: aggregateID},
+		Field{Key: FieldAggregateType, Value: r.aggregateType},
+		Field{Key: FieldEvents, Value: len(events)},
+		Field{Key: FieldError, Value: err},
+	)
+}
+
+// logSave logs the outcome of saving events for an aggregate.
+func (r *Repository) logSave(ctx context.Context, aggregateID string, events []es.Event, start time.Time, err error) {
+	fields := []Field{
+		{Key: FieldAggregateID, Value: aggregateID},
+		{Key: FieldAggregateType, Value: r.aggregateType},
+		{Key: FieldVersion, Value: events[len(events)-1].EventVersion()},
+		{Key: FieldEvents, Value: len(events)},
+		{Key: FieldDuration, Value: time.Since(start)},
+	}
+
+	switch {
+	case err == nil:
+		r.logger.Log(ctx, LevelDebug, "events saved", fields...)
+	case errors.Is(err, es.ErrConflict):
+		r.logger.Log(ctx, LevelWarn, "save conflict", append(fields, Field{Key: FieldError, Value: err})...)
+	default:
+		r.logger.Log(ctx, LevelError, "save failed", append(fields, Field{Key: FieldError, Value: err})...)
+	}
 }
 
 // Load retrieves the specified aggregate from the underlying store
@@ -167,87 +524,513 @@ func (r *Repository) Load(ctx context.Context, aggregateID string) (es.Aggregate
 // loadVersion loads the specified aggregate from the store and returns both the Aggregate and the
 // current version number of the aggregate
 func (r *Repository) loadVersion(ctx context.Context, aggregateID string) (es.Aggregate, int64, error) {
-	history, err := r.store.Load(ctx, aggregateID, 0, 0)
-	if err != nil {
+	ctx, span := r.startSpan(ctx, trace.SpanLoad, aggregateID)
+	defer span.End()
+
+	start := time.Now()
+
+	aggregate, version, ok := r.cache.get(aggregateID)
+	if !ok {
+		aggregate = r.New()
+	}
+
+	aggregate, latest, entryCount, err := r.fold(ctx, aggregateID, aggregate, version)
+	if err == nil && latest == 0 {
+		err = r.notFound(aggregateID)
+	}
+
+	fields := []Field{
+		{Key: FieldAggregateID, Value: aggregateID},
+		{Key: FieldAggregateType, Value: r.aggregateType},
+		{Key: FieldVersion, Value: latest},
+		{Key: FieldEvents, Value: entryCount},
+		{Key: FieldDuration, Value: time.Since(start)},
+	}
+
+	r.metrics.Observe(metrics.LoadDuration, time.Since(start).Seconds(), r.loadLabels(err)...)
+	r.metrics.Observe(metrics.LoadEvents, float64(entryCount), r.loadLabels(err)...)
+
+	switch {
+	case isNotFound(err):
+		r.cache.remove(aggregateID)
+		r.logger.Log(ctx, LevelDebug, "aggregate not found", fields...)
+		return nil, 0, err
+	case err != nil:
+		r.cache.remove(aggregateID)
+		r.logger.Log(ctx, LevelError, "load failed", append(fields, Field{Key: FieldError, Value: err})...)
+		span.RecordError(err)
 		return nil, 0, err
 	}
 
-	entryCount := len(history)
-	if entryCount == 0 {
-		return nil, 0, fmt.Errorf("unable to load %v, %s", r.New(), aggregateID)
+	span.SetAttributes(trace.Attribute{Key: trace.AttrVersion, Value: latest})
+
+	r.logger.Log(ctx, LevelDebug, "aggregate loaded", fields...)
+
+	if !ok || latest != version {
+		r.cache.put(aggregateID, aggregate, latest)
 	}
 
-	r.logf("Loaded %d event(s) for aggregate id, %s", entryCount, aggregateID)
-	aggregate := r.New()
+	return aggregate, latest, nil
+}
 
-	var version int64
+// fold applies the records stored after version to the aggregate and returns
+// the aggregate, the version of the last event applied and the number of
+// events applied.
+func (r *Repository) fold(ctx context.Context, aggregateID string, aggregate es.Aggregate, version int64) (es.Aggregate, int64, int, error) {
+	ctx, span := r.startSpan(ctx, trace.SpanFold, aggregateID)
+	defer span.End()
 
-	for _, record := range history {
-		event, err := r.serializer.UnmarshalEvent(record)
+	aggregate, latest, entryCount, err := r.foldRecords(ctx, aggregateID, aggregate, version)
+	if err != nil && !isNotFound(err) {
+		span.RecordError(err)
+	}
+	span.SetAttributes(trace.Attribute{Key: trace.AttrEvents, Value: entryCount})
+
+	return aggregate, latest, entryCount, err
+}
+
+// foldRecords implements fold. Only the store reporting es.ErrNotFound, from
+// opening or reading the stream, is returned as a *NotFoundError; a decode or
+// fold error wrapping es.ErrNotFound means the aggregate is corrupt rather
+// than missing.
+func (r *Repository) foldRecords(ctx context.Context, aggregateID string, aggregate es.Aggregate, version int64) (es.Aggregate, int64, int, error) {
+	var fromVersion int64
+	if version > 0 {
+		fromVersion = version + 1
+	}
+
+	cursor, err := es.Stream(ctx, r.store, aggregateID, fromVersion, 0)
+	if errors.Is(err, es.ErrNotFound) {
+		return nil, 0, 0, r.notFound(aggregateID)
+	} else if err != nil {
+		return nil, 0, 0, err
+	}
+	defer cursor.Close()
+
+	var entryCount int
+
+	for cursor.Next() {
+		record := cursor.Record()
+		event, err := es.UnmarshalEvent(ctx, r.serializer, record)
 		if err != nil {
-			return nil, 0, err
+			return nil, 0, 0, &DecodeError{
+				AggregateID:   aggregateID,
+				AggregateType: r.aggregateType,
+				Version:       record.Version,
+				Err:           err,
+			}
 		}
 
-		err = aggregate.On(event)
+		err = r.on(aggregateID, aggregate, event)
 		if err != nil {
-			eventType := event.EventType()
-			return nil, 0, fmt.Errorf("repository for %q aggregate was unable to handle event, %v: this is a programming error which may be solved by updating the On function of the repository: error %s", r.prototype.Name(), eventType, err)
+			return nil, 0, 0, &FoldError{
+				AggregateID:   aggregateID,
+				AggregateType: r.aggregateType,
+				EventType:     event.EventType(),
+				Version:       event.EventVersion(),
+				Err:           err,
+			}
 		}
 
 		version = event.EventVersion()
+		entryCount++
+	}
+
+	if err := cursor.Err(); errors.Is(err, es.ErrNotFound) {
+		return nil, 0, 0, r.notFound(aggregateID)
+	} else if err != nil {
+		return nil, 0, 0, err
 	}
 
-	return aggregate, version, nil
+	return aggregate, version, entryCount, nil
+}
+
+// notFound builds the error returned when the aggregate has no history.
+func (r *Repository) notFound(aggregateID string) error {
+	return &NotFoundError{AggregateID: aggregateID, AggregateType: r.aggregateType}
 }
 
 // Apply executes the command specified and returns the current version of the
-// aggregate
+// aggregate. An aggregate which has not been stored is created when the store
+// reports es.ErrNotFound; every other load error is returned. Commands
+// implementing es.Expecter are rejected with an *ExpectationError when the
+// aggregate does not meet their expectation.
+//
+// When WithCommandQueue is configured the command waits for earlier commands
+// to the same aggregate and es.ErrBusy is returned if too many are waiting.
 func (r *Repository) Apply(ctx context.Context, command es.Command) (int64, error) {
+	result, err := r.run(ctx, command)
+	switch {
+	case errors.Is(err, es.ErrNoEventsProduced):
+		return -1, err
+	case err != nil:
+		return 0, err
+	}
+
+	return result.Version, nil
+}
+
+// Result is the outcome of a command applied by ApplyResult.
+type Result struct {
+	// Events are the events produced by the command as they were saved.
+	Events []es.Event
+
+	// Records are the records saved for Events, in the same order, carrying
+	// the version and metadata stored for each event.
+	Records es.History
+
+	// Aggregate is the aggregate with Events folded into it.
+	Aggregate es.Aggregate
+
+	// Version is the version of the aggregate after the command.
+	Version int64
+}
+
+// ApplyResult executes the command as Apply does and returns the events
+// saved, their records and the folded aggregate so callers need not load the
+// aggregate again.
+//
+// The events are folded into the aggregate only once they have been saved. If
+// On can not handle them a *FoldError is returned together with a Result whose
+// Aggregate is nil; the events have been saved and published regardless.
+func (r *Repository) ApplyResult(ctx context.Context, command es.Command) (*Result, error) {
+	result, err := r.run(ctx, command)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.foldEvents(result.Events[0].AggregateID(), result.Aggregate, result.Events); err != nil {
+		result.Aggregate = nil
+		return result, err
+	}
+
+	return result, nil
+}
+
+// run applies the command, through the command queue when one is configured.
+// The Aggregate of the Result returned is the aggregate before the command.
+func (r *Repository) run(ctx context.Context, command es.Command) (*Result, error) {
 	if command == nil {
-		return 0, errors.New("command provided to Repository.Apply must not be nil")
+		return nil, errors.New("command provided to Repository.Apply must not be nil")
 	}
 
 	aggregateID := command.AggregateID()
 	if aggregateID == "" {
-		return 0, errors.New("command provided to Repository.Apply must not contain a blank AggregateID")
+		return nil, errors.New("command provided to Repository.Apply must not contain a blank AggregateID")
 	}
 
-	aggregate, version, err := r.loadVersion(ctx, aggregateID)
+	if r.queues == nil {
+		return r.apply(ctx, aggregateID, command)
+	}
+
+	var result *Result
+	_, err := r.queues.do(ctx, aggregateID, func() (int64, error) {
+		var err error
+		result, err = r.apply(ctx, aggregateID, command)
+		return 0, err
+	})
 
+	return result, err
+}
+
+// apply implements run once the command may run.
+func (r *Repository) apply(ctx context.Context, aggregateID string, command es.Command) (result *Result, err error) {
+	ctx, span := r.startSpan(ctx, trace.SpanApply, aggregateID,
+		trace.Attribute{Key: trace.AttrCommandType, Value: command.EventType()},
+	)
+
+	start, outcome, produced := time.Now(), metrics.OutcomeError, 0
+	defer func() {
+		span.RecordError(err)
+		if result != nil {
+			span.SetAttributes(trace.Attribute{Key: trace.AttrVersion, Value: result.Version})
+		}
+		span.End()
+		r.measureApply(command, start, outcome, produced)
+	}()
+
+	aggregate, _, events, err := r.decide(ctx, aggregateID, command)
 	if err != nil {
-		aggregate = r.New()
+		outcome = applyOutcome(err)
+		return nil, err
 	}
 
-	h, ok := aggregate.(es.CommandHandler)
-	if !ok {
-		return 0, fmt.Errorf("aggregate, %T, does not implement CommandHandler", aggregate)
+	records, err := r.save(ctx, aggregateID, events)
+	if err != nil {
+		outcome = applyOutcome(err)
+		return nil, err
 	}
 
-	events, err := h.Apply(ctx, command)
-	if err != nil {
-		return 0, err
+	outcome, produced = metrics.OutcomeOK, len(events)
+	r.publish(ctx, events)
+
+	return &Result{
+		Events:    events,
+		Records:   records,
+		Aggregate: aggregate,
+		Version:   events[len(events)-1].EventVersion(),
+	}, nil
+}
+
+// decide loads the aggregate and applies the command to it, returning the
+// aggregate, its version before the command and the stamped and validated
+// events the command produced. Nothing is saved.
+func (r *Repository) decide(ctx context.Context, aggregateID string, command es.Command) (es.Aggregate, int64, []es.Event, error) {
+	// Only an aggregate without history starts fresh; any other failure to
+	// load must not be mistaken for a new aggregate.
+	aggregate, version, err := r.loadVersion(ctx, aggregateID)
+	if isNotFound(err) {
+		aggregate, version = r.New(), 0
+	} else if err != nil {
+		return nil, 0, nil, err
+	}
+
+	events, err := r.decideOn(ctx, aggregateID, aggregate, version, command)
+	if err != nil && !errors.Is(err, es.ErrNoEventsProduced) {
+		return nil, 0, nil, err
+	}
+
+	return aggregate, version, events, err
+}
+
+// decideOn applies the command to the aggregate at the version given and
+// returns the stamped and validated events the command produced.
+func (r *Repository) decideOn(ctx context.Context, aggregateID string, aggregate es.Aggregate, version int64, command es.Command) ([]es.Event, error) {
+	if err := expect(command, version); err != nil {
+		r.logReject(ctx, command, version, err)
+		return nil, err
+	}
+
+	handleCtx, handleSpan := r.startSpan(ctx, trace.SpanHandle, aggregateID)
+	events, err := r.handle(handleCtx, aggregate, version, command)
+	handleSpan.RecordError(err)
+	handleSpan.End()
+
+	// A handler which panics or can not be called has failed rather than
+	// rejected the command.
+	var rejected *CommandRejectedError
+	switch {
+	case errors.As(err, &rejected):
+		r.logReject(ctx, command, version, err)
+		return nil, err
+	case err != nil:
+		r.logger.Log(ctx, LevelError, "command handler failed",
+			Field{Key: FieldAggregateID, Value: aggregateID},
+			Field{Key: FieldAggregateType, Value: r.aggregateType},
+			Field{Key: FieldCommandType, Value: command.EventType()},
+			Field{Key: FieldVersion, Value: version},
+			Field{Key: FieldError, Value: err},
+		)
+		return nil, err
 	}
 
 	if len(events) == 0 {
-		return -1, es.ErrNoEventsProduced
+		return nil, es.ErrNoEventsProduced
 	}
 
-	err = r.Save(ctx, events...)
+	r.stamp(aggregateID, version, events)
+
+	_, validateSpan := r.startSpan(ctx, trace.SpanValidate, aggregateID)
+	err = validate(aggregateID, version, events)
+	validateSpan.RecordError(err)
+	validateSpan.End()
 	if err != nil {
-		return 0, err
+		r.logUnsaved(ctx, "invalid events", aggregateID, events, err)
+		return nil, err
 	}
-	version = events[len(events)-1].EventVersion()
 
-	// publish events to observers
-	if r.observers != nil {
-		for _, event := range events {
-			for _, observer := range r.observers {
-				observer(event)
+	return events, nil
+}
+
+// applyOutcome classifies the error of a command as a metrics outcome.
+func applyOutcome(err error) string {
+	var (
+		rejected    *CommandRejectedError
+		expectation *ExpectationError
+	)
+	if errors.As(err, &rejected) || errors.As(err, &expectation) {
+		return metrics.OutcomeRejected
+	}
+	return errorOutcome(err)
+}
+
+// errorOutcome classifies an error as a metrics outcome, counting a recovered
+// panic apart from other errors.
+func errorOutcome(err error) string {
+	var panicked *PanicError
+	if errors.As(err, &panicked) {
+		return metrics.OutcomePanic
+	}
+	return metrics.Outcome(err)
+}
+
+// measureApply records the outcome and duration of a command and, when it
+// succeeded, the number of events produced.
+func (r *Repository) measureApply(command es.Command, start time.Time, outcome string, produced int) {
+	labels := []metrics.Label{
+		{Name: metrics.LabelAggregateType, Value: r.aggregateType},
+		{Name: metrics.LabelCommandType, Value: command.EventType()},
+		{Name: metrics.LabelOutcome, Value: outcome},
+	}
+
+	r.metrics.Add(metrics.ApplyTotal, 1, labels...)
+	r.metrics.Observe(metrics.ApplyDuration, time.Since(start).Seconds(), labels...)
+	if outcome == metrics.OutcomeOK {
+		r.metrics.Observe(metrics.ApplyEvents, float64(produced), labels...)
+	}
+}
+
+// loadLabels labels the metrics of a load.
+func (r *Repository) loadLabels(err error) []metrics.Label {
+	return []metrics.Label{
+		{Name: metrics.LabelAggregateType, Value: r.aggregateType},
+		{Name: metrics.LabelOutcome, Value: errorOutcome(err)},
+	}
+}
+
+// on folds the event into the aggregate, recovering a panic as a
+// *PanicError.
+func (r *Repository) on(aggregateID string, aggregate es.Aggregate, event es.Event) (err error) {
+	if !r.strictPanics {
+		defer func() {
+			if v := recover(); v != nil {
+				p := &PanicError{
+					AggregateID:   aggregateID,
+					AggregateType: r.aggregateType,
+					Value:         v,
+					Stack:         debug.Stack(),
+				}
+				if event != nil {
+					p.EventType, p.Version = event.EventType(), event.EventVersion()
+				}
+				err = p
+			}
+		}()
+	}
+
+	return aggregate.On(event)
+}
+
+// observe notifies the observer of the event, recovering a panic as a
+// *PanicError.
+func (r *Repository) observe(ctx context.Context, observer ObserverFunc, event es.Event) (err error) {
+	if !r.strictPanics {
+		defer func() {
+			if v := recover(); v != nil {
+				err = &PanicError{
+					AggregateID:   event.AggregateID(),
+					AggregateType: r.aggregateType,
+					EventType:     event.EventType(),
+					Version:       event.EventVersion(),
+					Value:         v,
+					Stack:         debug.Stack(),
+				}
 			}
+		}()
+	}
+
+	return observer(ctx, event)
+}
+
+// startSpan starts a span attributed with the aggregate.
+func (r *Repository) startSpan(ctx context.Context, name, aggregateID string, attrs ...trace.Attribute) (context.Context, trace.Span) {
+	attrs = append([]trace.Attribute{
+		{Key: trace.AttrAggregateID, Value: aggregateID},
+		{Key: trace.AttrAggregateType, Value: r.aggregateType},
+	}, attrs...)
+	return r.tracer.Start(ctx, name, attrs...)
+}
+
+// logReject logs a command rejected by its expectation or handler.
+func (r *Repository) logReject(ctx context.Context, command es.Command, version int64, err error) {
+	r.logger.Log(ctx, LevelInfo, "command rejected",
+		Field{Key: FieldAggregateID, Value: command.AggregateID()},
+		Field{Key: FieldAggregateType, Value: r.aggregateType},
+		Field{Key: FieldCommandType, Value: command.EventType()},
+		Field{Key: FieldVersion, Value: version},
+		Field{Key: FieldError, Value: err},
+	)
+}
+
+// handle applies the command to the aggregate producing new events. Errors
+// returned by the handler are wrapped in a *CommandRejectedError.
+func (r *Repository) handle(ctx context.Context, aggregate es.Aggregate, version int64, command es.Command) (events []es.Event, err error) {
+	if !r.strictPanics {
+		defer func() {
+			if v := recover(); v != nil {
+				events, err = nil, &PanicError{
+					AggregateID:   command.AggregateID(),
+					AggregateType: r.aggregateType,
+					CommandType:   command.EventType(),
+					Version:       version,
+					Value:         v,
+					Stack:         debug.Stack(),
+				}
+			}
+		}()
+	}
+
+	if r.handler != nil {
+		events, err = r.handler(ctx, aggregate, command)
+	} else if h, ok := aggregate.(es.CommandHandler); ok {
+		events, err = h.Apply(ctx, command)
+	} else {
+		return nil, fmt.Errorf("aggregate, %T, does not implement CommandHandler", aggregate)
+	}
+
+	if err != nil {
+		return nil, &CommandRejectedError{
+			AggregateID:   command.AggregateID(),
+			AggregateType: r.aggregateType,
+			CommandType:   command.EventType(),
+			Version:       version,
+			Err:           err,
 		}
 	}
 
-	return version, nil
+	return events, nil
+}
+
+// stamp assigns the aggregate id and the versions following version to the
+// events when stamping is enabled.
+func (r *Repository) stamp(aggregateID string, version int64, events []es.Event) {
+	if !r.stamping {
+		return
+	}
+
+	at := time.Now().UTC()
+	for i, event := range events {
+		if s, ok := event.(es.Stamper); ok {
+			s.Stamp(aggregateID, version+int64(i)+1, at)
+		}
+	}
+}
+
+// publish notifies the observers of saved events and logs any failures.
+func (r *Repository) publish(ctx context.Context, events []es.Event) {
+	if r.observers == nil {
+		return
+	}
+
+	ctx, span := r.tracer.Start(ctx, trace.SpanPublish,
+		trace.Attribute{Key: trace.AttrAggregateType, Value: r.aggregateType},
+		trace.Attribute{Key: trace.AttrEvents, Value: len(events)},
+	)
+	defer span.End()
+
+	for _, event := range events {
+		for _, observer := range r.observers {
+			if err := r.observe(ctx, observer, event); err != nil {
+				r.logger.Log(ctx, LevelError, "observer failed",
+					Field{Key: FieldAggregateID, Value: event.AggregateID()},
+					Field{Key: FieldAggregateType, Value: r.aggregateType},
+					Field{Key: FieldEventType, Value: event.EventType()},
+					Field{Key: FieldVersion, Value: event.EventVersion()},
+					Field{Key: FieldError, Value: err},
+				)
+			}
+		}
+	}
 }
 
 // Store returns the underlying Store
@@ -255,6 +1038,18 @@ func (r *Repository) Store() es.Store {
 	return r.store
 }
 
+// QueueStats reports the aggregates being served by the command queue enabled
+// by WithCommandQueue and the commands waiting for them.
+func (r *Repository) QueueStats() QueueStats {
+	return r.queues.stats()
+}
+
+// CacheStats reports the hits, misses and evictions of the aggregate cache
+// enabled by WithCache.
+func (r *Repository) CacheStats() CacheStats {
+	return r.cache.stats()
+}
+
 // Serializer returns the underlying serializer
 func (r *Repository) Serializer() es.Serializer {
 	return r.serializer
@@ -0,0 +1,138 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/repository"
+)
+
+// Counter is an aggregate used by the repository tests.
+type Counter struct {
+	ID      string
+	Version int64
+	Value   int
+}
+
+// On implements the eventsource.Aggregate interface.
+func (c *Counter) On(event es.Event) error {
+	switch e := event.(type) {
+	case *Incremented:
+		c.ID = e.ID
+		c.Version = e.Version
+		c.Value += e.By
+		return nil
+	}
+
+	return fmt.Errorf("unhandled event %T", event)
+}
+
+// Apply implements the eventsource.CommandHandler interface.
+func (c *Counter) Apply(_ context.Context, command es.Command) ([]es.Event, error) {
+	switch cmd := command.(type) {
+	case Increment:
+		if cmd.By == 0 {
+			return nil, nil
+		}
+		if cmd.By < 0 {
+			return nil, errors.New("counters may only be incremented")
+		}
+		version := c.Version + 1
+		if cmd.Version != 0 {
+			version = cmd.Version
+		}
+		return []es.Event{&Incremented{
+			ID:      cmd.ID,
+			Version: version,
+			At:      time.Now(),
+			By:      cmd.By,
+		}}, nil
+	}
+
+	return nil, fmt.Errorf("unhandled command %T", command)
+}
+
+// Increment asks a Counter to increase its value.
+type Increment struct {
+	es.CommandModel
+	By int
+
+	// Version overrides the version of the event produced to simulate a
+	// faulty handler.
+	Version int64
+}
+
+// increment builds an Increment command for the counter.
+func increment(id string, by int) Increment {
+	return Increment{
+		CommandModel: es.CommandModel{ID: id, Type: "increment"},
+		By:           by,
+	}
+}
+
+// Incremented records that a Counter's value increased.
+type Incremented struct {
+	ID      string
+	Version int64
+	At      time.Time
+	By      int
+}
+
+// AggregateID implements the eventsource.Event interface.
+func (e Incremented) AggregateID() string { return e.ID }
+
+// EventVersion implements the eventsource.Event interface.
+func (e Incremented) EventVersion() int64 { return e.Version }
+
+// EventAt implements the eventsource.Event interface.
+func (e Incremented) EventAt() time.Time { return e.At }
+
+// EventType implements the eventsource.Event interface.
+func (e Incremented) EventType() string { return "incremented" }
+
+// TestNew asserts repositories built from a prototype and from a type
+// parameter both build new aggregates of the aggregate type.
+func TestNew(t *testing.T) {
+	for name, build := range map[string]func() (*repository.Repository, error){
+		"prototype": func() (*repository.Repository, error) {
+			return repository.New(&Counter{}, []es.Event{&Incremented{}})
+		},
+		"type": func() (*repository.Repository, error) {
+			return repository.NewFor[Counter]([]es.Event{&Incremented{}})
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			repo, err := build()
+			require.NoError(t, err)
+			assert.Equal(t, "Counter", repo.AggregateType())
+			assert.IsType(t, &Counter{}, repo.New())
+
+			_, err = repo.Apply(context.Background(), increment("a", 2))
+			require.NoError(t, err)
+
+			aggregate, err := repo.Load(context.Background(), "a")
+			require.NoError(t, err)
+			assert.Equal(t, 2, aggregate.(*Counter).Value)
+		})
+	}
+
+	_, err := repository.New(nil, nil)
+	assert.Error(t, err)
+}
+
+// TestWithHandlerOnce asserts a second handler can not replace the first.
+func TestWithHandlerOnce(t *testing.T) {
+	handler := func(context.Context, es.Aggregate, es.Command) ([]es.Event, error) {
+		return nil, nil
+	}
+
+	_, err := repository.New(&Counter{}, nil, repository.WithHandler(handler), repository.WithHandler(handler))
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/repository"
+)
+
+// TestApplyResult asserts the events, records and folded aggregate of an
+// applied command are returned.
+func TestApplyResult(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}})
+	require.NoError(t, err)
+
+	_, err = repo.Apply(ctx, increment("a", 1))
+	require.NoError(t, err)
+
+	result, err := repo.ApplyResult(ctx, increment("a", 2))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Version)
+	assert.Equal(t, 3, result.Aggregate.(*Counter).Value)
+	require.Len(t, result.Events, 1)
+	require.Len(t, result.Records, 1)
+	assert.Equal(t, int64(2), result.Records[0].Version)
+	assert.Equal(t, "Counter", result.Records[0].AggregateType)
+
+	history, err := repo.Store().Load(ctx, "a", 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, history, result.Records)
+
+	_, err = repo.ApplyResult(ctx, increment("a", 0))
+	assert.True(t, errors.Is(err, es.ErrNoEventsProduced))
+}
+
+// TestApplyResultFoldError asserts events are saved before they are folded,
+// so a fold failure is returned alongside the saved events.
+func TestApplyResultFoldError(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := repository.New(&Orphan{}, []es.Event{&Incremented{}})
+	require.NoError(t, err)
+
+	result, err := repo.ApplyResult(ctx, increment("a", 1))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Aggregate.(*Orphan).Value)
+
+	result, err = repo.ApplyResult(ctx, increment("a", 1))
+	var fold *repository.FoldError
+	require.True(t, errors.As(err, &fold), "expected a FoldError but found %v", err)
+	require.NotNil(t, result)
+	assert.Nil(t, result.Aggregate)
+	assert.Equal(t, int64(2), result.Version)
+
+	history, err := repo.Store().Load(ctx, "a", 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/trace"
+)
+
+// Simulation is the outcome a command would have if it were applied.
+type Simulation struct {
+	// Events are the stamped and validated events the command would produce.
+	Events []es.Event
+
+	// Aggregate is the aggregate with Events folded into it.
+	Aggregate es.Aggregate
+
+	// Version is the version the aggregate would have once Events are saved.
+	Version int64
+}
+
+// Simulate runs the command through the same load, handle and validation path
+// as Apply without saving the events produced or notifying observers. The
+// errors returned match those of Apply, including es.ErrNoEventsProduced, so
+// callers may preview whether a command would be rejected. The events are
+// folded into the aggregate, failing with a *FoldError if On can not handle
+// them.
+//
+// Simulate does not wait behind the command queue enabled by
+// WithCommandQueue; the simulation reflects the aggregate as last saved.
+func (r *Repository) Simulate(ctx context.Context, command es.Command) (*Simulation, error) {
+	if command == nil {
+		return nil, errors.New("command provided to Repository.Simulate must not be nil")
+	}
+
+	aggregateID := command.AggregateID()
+	if aggregateID == "" {
+		return nil, errors.New("command provided to Repository.Simulate must not contain a blank AggregateID")
+	}
+
+	ctx, span := r.startSpan(ctx, trace.SpanSimulate, aggregateID,
+		trace.Attribute{Key: trace.AttrCommandType, Value: command.EventType()},
+	)
+	defer span.End()
+
+	aggregate, version, events, err := r.decide(ctx, aggregateID, command)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if err := r.foldEvents(aggregateID, aggregate, events); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &Simulation{
+		Events:    events,
+		Aggregate: aggregate,
+		Version:   version + int64(len(events)),
+	}, nil
+}
+
+// foldEvents folds events which have not been stored into the aggregate.
+func (r *Repository) foldEvents(aggregateID string, aggregate es.Aggregate, events []es.Event) error {
+	for _, event := range events {
+		if err := r.on(aggregateID, aggregate, event); err != nil {
+			return &FoldError{
+				AggregateID:   aggregateID,
+				AggregateType: r.aggregateType,
+				EventType:     event.EventType(),
+				Version:       event.EventVersion(),
+				Err:           err,
+			}
+		}
+	}
+	return nil
+}
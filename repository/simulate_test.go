@@ -0,0 +1,56 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/repository"
+)
+
+// TestSimulate asserts a simulated command returns its events and folded
+// aggregate without saving or notifying observers.
+func TestSimulate(t *testing.T) {
+	ctx := context.Background()
+	var observed int
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}},
+		repository.WithCache(10),
+		repository.WithObservers(func(es.Event) { observed++ }),
+	)
+	require.NoError(t, err)
+
+	_, err = repo.Apply(ctx, increment("a", 1))
+	require.NoError(t, err)
+
+	simulation, err := repo.Simulate(ctx, increment("a", 2))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), simulation.Version)
+	require.Len(t, simulation.Events, 1)
+	assert.Equal(t, int64(2), simulation.Events[0].EventVersion())
+	assert.Equal(t, 3, simulation.Aggregate.(*Counter).Value)
+	assert.Equal(t, 1, observed)
+
+	aggregate, err := repo.Load(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 1, aggregate.(*Counter).Value)
+	assert.Equal(t, int64(1), aggregate.(*Counter).Version)
+
+	_, err = repo.Simulate(ctx, increment("a", -1))
+	var rejected *repository.CommandRejectedError
+	assert.True(t, errors.As(err, &rejected), "expected a CommandRejectedError but found %v", err)
+
+	_, err = repo.Simulate(ctx, increment("a", 0))
+	assert.True(t, errors.Is(err, es.ErrNoEventsProduced))
+
+	simulation, err = repo.Simulate(ctx, increment("new", 5))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), simulation.Version)
+
+	_, err = repo.Load(ctx, "new")
+	assert.True(t, errors.Is(err, es.ErrNotFound))
+}
@@ -0,0 +1,75 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/repository"
+)
+
+// Noted records a note against a Notebook without computing its own id,
+// version or timestamp.
+type Noted struct {
+	es.EventModel
+	Text string
+}
+
+// EventType implements the eventsource.Event interface.
+func (e Noted) EventType() string { return "noted" }
+
+// Notebook is an aggregate whose commands rely on the repository to stamp
+// events.
+type Notebook struct {
+	Version int64
+	Notes   []string
+}
+
+// On implements the eventsource.Aggregate interface.
+func (n *Notebook) On(event es.Event) error {
+	e := event.(*Noted)
+	n.Version = e.Version
+	n.Notes = append(n.Notes, e.Text)
+	return nil
+}
+
+// Apply implements the eventsource.CommandHandler interface.
+func (n *Notebook) Apply(_ context.Context, command es.Command) ([]es.Event, error) {
+	return []es.Event{&Noted{Text: command.EventType()}, &Noted{Text: "again"}}, nil
+}
+
+// TestStamping asserts the repository assigns ids, versions and timestamps.
+func TestStamping(t *testing.T) {
+	ctx := context.Background()
+
+	var observed []*Noted
+	repo, err := repository.New(&Notebook{}, []es.Event{&Noted{}},
+		repository.WithStamping(),
+		repository.WithObservers(func(event es.Event) {
+			observed = append(observed, event.(*Noted))
+		}),
+	)
+	require.NoError(t, err)
+
+	version, err := repo.Apply(ctx, es.CommandModel{ID: "n", Type: "first"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), version)
+
+	version, err = repo.Apply(ctx, es.CommandModel{ID: "n", Type: "second"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), version)
+
+	require.Len(t, observed, 4)
+	for i, event := range observed {
+		assert.Equal(t, "n", event.ID)
+		assert.Equal(t, int64(i+1), event.Version)
+		assert.False(t, event.At.IsZero())
+	}
+
+	aggregate, err := repo.Load(ctx, "n")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "again", "second", "again"}, aggregate.(*Notebook).Notes)
+}
@@ -0,0 +1,184 @@
+// Package typed provides a type-safe Repository for aggregates of a known
+// type. It wraps repository.Repository so loads return the concrete aggregate
+// and commands may be handled by functions typed by both the aggregate and
+// the command.
+package typed
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/repository"
+)
+
+// Handler applies a command of type C to an aggregate of type A to generate a
+// new set of events.
+type Handler[A any, C es.Command] func(ctx context.Context, aggregate *A, command C) ([]es.Event, error)
+
+// handlerFunc attempts to handle a command and reports whether it was
+// handled.
+type handlerFunc[A any] func(ctx context.Context, aggregate *A, command es.Command) ([]es.Event, bool, error)
+
+// Repository saves and loads aggregates of type A. The pointer type, *A, must
+// implement es.Aggregate.
+type Repository[A any] struct {
+	repo     *repository.Repository
+	cast     func(es.Aggregate) *A
+	handlers []handlerFunc[A]
+	m        sync.RWMutex
+}
+
+// New creates a new Repository for aggregates of type A. The events and
+// options are those accepted by repository.New, except repository.WithHandler
+// which returns an error; register handlers with Handle instead.
+func New[A any, P interface {
+	*A
+	es.Aggregate
+}](events []es.Event, opts ...repository.Option) (*Repository[A], error) {
+	r := &Repository[A]{
+		cast: func(aggregate es.Aggregate) *A {
+			return (*A)(aggregate.(P))
+		},
+	}
+
+	opts = append([]repository.Option{repository.WithHandler(r.handle)}, opts...)
+
+	repo, err := repository.NewFor[A, P](events, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	r.repo = repo
+
+	return r, nil
+}
+
+// Handle registers a handler for commands of type C. Commands without a
+// registered handler are applied by the aggregate's es.CommandHandler
+// implementation. Handle is safe to call while the repository is in use.
+func Handle[A any, C es.Command](r *Repository[A], handler Handler[A, C]) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.handlers = append(r.handlers, func(ctx context.Context, aggregate *A, command es.Command) ([]es.Event, bool, error) {
+		c, ok := command.(C)
+		if !ok {
+			return nil, false, nil
+		}
+
+		events, err := handler(ctx, aggregate, c)
+		return events, true, err
+	})
+}
+
+// handle implements repository.HandlerFunc by dispatching to the typed
+// handlers.
+func (r *Repository[A]) handle(ctx context.Context, aggregate es.Aggregate, command es.Command) ([]es.Event, error) {
+	a := r.cast(aggregate)
+
+	r.m.RLock()
+	handlers := r.handlers
+	r.m.RUnlock()
+
+	for _, handler := range handlers {
+		events, ok, err := handler(ctx, a, command)
+		if ok {
+			return events, err
+		}
+	}
+
+	h, ok := aggregate.(es.CommandHandler)
+	if !ok {
+		return nil, fmt.Errorf("no handler registered for command %T and aggregate, %T, does not implement CommandHandler", command, aggregate)
+	}
+
+	return h.Apply(ctx, command)
+}
+
+// New returns a new instance of the aggregate.
+func (r *Repository[A]) New() *A {
+	return new(A)
+}
+
+// Load retrieves the specified aggregate from the underlying store.
+func (r *Repository[A]) Load(ctx context.Context, aggregateID string) (*A, error) {
+	aggregate, err := r.repo.Load(ctx, aggregateID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.cast(aggregate), nil
+}
+
+// Apply executes the command specified and returns the current version of
+// the aggregate.
+func (r *Repository[A]) Apply(ctx context.Context, command es.Command) (int64, error) {
+	return r.repo.Apply(ctx, command)
+}
+
+// Result is the outcome of a command applied by ApplyResult.
+type Result[A any] struct {
+	Events    []es.Event
+	Records   es.History
+	Aggregate *A
+	Version   int64
+}
+
+// ApplyResult executes the command and returns the events saved, their
+// records and the folded aggregate. See repository.Repository.ApplyResult.
+func (r *Repository[A]) ApplyResult(ctx context.Context, command es.Command) (*Result[A], error) {
+	result, err := r.repo.ApplyResult(ctx, command)
+	if result == nil {
+		return nil, err
+	}
+
+	typed := &Result[A]{
+		Events:  result.Events,
+		Records: result.Records,
+		Version: result.Version,
+	}
+	if result.Aggregate != nil {
+		typed.Aggregate = r.cast(result.Aggregate)
+	}
+
+	return typed, err
+}
+
+// Simulation is the outcome a command would have if it were applied.
+type Simulation[A any] struct {
+	Events    []es.Event
+	Aggregate *A
+	Version   int64
+}
+
+// Simulate runs the command without saving the events produced or notifying
+// observers. See repository.Repository.Simulate.
+func (r *Repository[A]) Simulate(ctx context.Context, command es.Command) (*Simulation[A], error) {
+	simulation, err := r.repo.Simulate(ctx, command)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Simulation[A]{
+		Events:    simulation.Events,
+		Aggregate: r.cast(simulation.Aggregate),
+		Version:   simulation.Version,
+	}, nil
+}
+
+// Save persists the events into the underlying store.
+func (r *Repository[A]) Save(ctx context.Context, events ...es.Event) error {
+	return r.repo.Save(ctx, events...)
+}
+
+// UnitOfWork begins a new unit of work against the repository.
+func (r *Repository[A]) UnitOfWork() *repository.UnitOfWork {
+	return r.repo.UnitOfWork()
+}
+
+// Untyped returns the underlying repository.Repository.
+func (r *Repository[A]) Untyped() *repository.Repository {
+	return r.repo
+}
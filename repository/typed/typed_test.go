@@ -0,0 +1,100 @@
+package typed_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/repository"
+	"github.com/aarongreenlee/eventsource/repository/typed"
+)
+
+// Account is an aggregate whose commands are handled by typed handlers.
+type Account struct {
+	Version int64
+	Balance int
+}
+
+// On implements the eventsource.Aggregate interface.
+func (a *Account) On(event es.Event) error {
+	e, ok := event.(*Deposited)
+	if !ok {
+		return fmt.Errorf("unhandled event %T", event)
+	}
+	a.Version = e.Version
+	a.Balance += e.Amount
+	return nil
+}
+
+// Deposit asks an Account to increase its balance.
+type Deposit struct {
+	es.CommandModel
+	Amount int
+}
+
+// Deposited records an increase to an Account balance.
+type Deposited struct {
+	es.EventModel
+	Amount int
+}
+
+// EventType implements the eventsource.Event interface.
+func (e Deposited) EventType() string { return "deposited" }
+
+// TestRepository asserts commands are routed to typed handlers and loads
+// return the concrete aggregate.
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := typed.New[Account]([]es.Event{&Deposited{}}, repository.WithStamping())
+	require.NoError(t, err)
+
+	typed.Handle(repo, func(_ context.Context, a *Account, cmd Deposit) ([]es.Event, error) {
+		if cmd.Amount <= 0 {
+			return nil, errors.New("deposits must be positive")
+		}
+		return []es.Event{&Deposited{Amount: cmd.Amount}}, nil
+	})
+
+	_, err = repo.Apply(ctx, Deposit{CommandModel: es.CommandModel{ID: "acc"}, Amount: 10})
+	require.NoError(t, err)
+	version, err := repo.Apply(ctx, Deposit{CommandModel: es.CommandModel{ID: "acc"}, Amount: 5})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), version)
+
+	account, err := repo.Load(ctx, "acc")
+	require.NoError(t, err)
+	assert.Equal(t, 15, account.Balance)
+	assert.Equal(t, "Account", repo.Untyped().AggregateType())
+
+	result, err := repo.ApplyResult(ctx, Deposit{CommandModel: es.CommandModel{ID: "other"}, Amount: 7})
+	require.NoError(t, err)
+	assert.Equal(t, 7, result.Aggregate.Balance)
+	assert.Equal(t, int64(1), result.Version)
+
+	simulation, err := repo.Simulate(ctx, Deposit{CommandModel: es.CommandModel{ID: "acc"}, Amount: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 16, simulation.Aggregate.Balance)
+	assert.Equal(t, int64(3), simulation.Version)
+
+	// Account does not implement CommandHandler so unregistered commands
+	// are rejected.
+	_, err = repo.Apply(ctx, es.CommandModel{ID: "acc", Type: "close"})
+	assert.Error(t, err)
+}
+
+// TestNewRejectsHandler asserts a handler option can not replace the typed
+// handlers.
+func TestNewRejectsHandler(t *testing.T) {
+	handler := func(context.Context, es.Aggregate, es.Command) ([]es.Event, error) {
+		return nil, nil
+	}
+
+	_, err := typed.New[Account]([]es.Event{&Deposited{}}, repository.WithHandler(handler))
+	assert.Error(t, err)
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/trace"
+)
+
+// UnitOfWork collects commands for one or more aggregates so the events they
+// produce are validated together and saved atomically. A UnitOfWork is not
+// safe for concurrent use and should be discarded after Commit.
+type UnitOfWork struct {
+	repo     *Repository
+	commands []es.Command
+}
+
+// UnitOfWork begins a new unit of work against the repository.
+func (r *Repository) UnitOfWork() *UnitOfWork {
+	return &UnitOfWork{repo: r}
+}
+
+// Add queues commands to be applied when the unit of work is committed.
+// Commands are applied in the order they were added and several commands may
+// target the same aggregate.
+func (u *UnitOfWork) Add(commands ...es.Command) *UnitOfWork {
+	u.commands = append(u.commands, commands...)
+	return u
+}
+
+// pendingAggregate tracks an aggregate while the unit of work is committed.
+type pendingAggregate struct {
+	aggregate es.Aggregate
+	version   int64
+	events    []es.Event
+	folded    int
+}
+
+// pendingCommand tracks a command while the unit of work is committed so its
+// span and metrics report the outcome of the unit.
+type pendingCommand struct {
+	command  es.Command
+	span     trace.Span
+	start    time.Time
+	produced int
+	version  int64
+	err      error
+}
+
+// Commit applies every command and saves the events produced for all
+// aggregates in a single transaction. Nothing is saved unless every command
+// is accepted and every event is valid. Commit fails with
+// es.ErrTxUnsupported, before any command is applied, when the underlying
+// store does not implement es.TxStore.
+//
+// Each command is traced and measured as Apply would, with the outcome of the
+// unit as a whole. When WithCommandQueue is configured the unit waits for the
+// earlier commands to every aggregate it changes and holds their queues until
+// it has been saved.
+//
+// The current version of each aggregate changed is returned keyed by the
+// aggregate id.
+func (u *UnitOfWork) Commit(ctx context.Context) (map[string]int64, error) {
+	r := u.repo
+
+	store, ok := r.store.(es.TxStore)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", es.ErrTxUnsupported, r.store)
+	}
+
+	aggregateIDs := make([]string, 0, len(u.commands))
+	seen := make(map[string]bool, len(u.commands))
+
+	for i, command := range u.commands {
+		if command == nil {
+			return nil, fmt.Errorf("command %d provided to UnitOfWork must not be nil", i)
+		}
+
+		aggregateID := command.AggregateID()
+		if aggregateID == "" {
+			return nil, fmt.Errorf("command %d provided to UnitOfWork must not contain a blank AggregateID", i)
+		}
+
+		if !seen[aggregateID] {
+			seen[aggregateID] = true
+			aggregateIDs = append(aggregateIDs, aggregateID)
+		}
+	}
+
+	// Queues are always acquired in the same order so units of work sharing
+	// aggregates can not deadlock.
+	sort.Strings(aggregateIDs)
+
+	var versions map[string]int64
+	err := r.exclusive(ctx, aggregateIDs, func() error {
+		var err error
+		versions, err = u.commit(ctx, store)
+		return err
+	})
+
+	return versions, err
+}
+
+// exclusive runs fn once the earlier commands queued for every aggregate have
+// run, holding the queue of each aggregate until fn returns.
+func (r *Repository) exclusive(ctx context.Context, aggregateIDs []string, fn func() error) error {
+	if r.queues == nil || len(aggregateIDs) == 0 {
+		return fn()
+	}
+
+	_, err := r.queues.do(ctx, aggregateIDs[0], func() (int64, error) {
+		return 0, r.exclusive(ctx, aggregateIDs[1:], fn)
+	})
+
+	return err
+}
+
+// commit implements Commit once the unit of work may run.
+func (u *UnitOfWork) commit(ctx context.Context, store es.TxStore) (versions map[string]int64, err error) {
+	r := u.repo
+
+	pending := make(map[string]*pendingAggregate, len(u.commands))
+	order := make([]string, 0, len(u.commands))
+	commands := make([]*pendingCommand, 0, len(u.commands))
+
+	defer func() {
+		for _, c := range commands {
+			outcome := c.err
+			switch {
+			case outcome != nil:
+			case err != nil:
+				outcome = err
+			case c.produced == 0:
+				outcome = es.ErrNoEventsProduced
+			}
+
+			c.span.RecordError(outcome)
+			if outcome == nil {
+				c.span.SetAttributes(trace.Attribute{Key: trace.AttrVersion, Value: c.version})
+			}
+			c.span.End()
+			r.measureApply(c.command, c.start, applyOutcome(outcome), c.produced)
+		}
+	}()
+
+	for i, command := range u.commands {
+		aggregateID := command.AggregateID()
+
+		commandCtx, span := r.startSpan(ctx, trace.SpanApply, aggregateID,
+			trace.Attribute{Key: trace.AttrCommandType, Value: command.EventType()},
+		)
+		c := &pendingCommand{command: command, span: span, start: time.Now()}
+		commands = append(commands, c)
+
+		p, ok := pending[aggregateID]
+		if !ok {
+			aggregate, version, err := r.loadVersion(commandCtx, aggregateID)
+			if isNotFound(err) {
+				aggregate, version = r.New(), 0
+			} else if err != nil {
+				c.err = err
+				return nil, fmt.Errorf("command %d for aggregate %q: %w", i, aggregateID, err)
+			}
+
+			p = &pendingAggregate{aggregate: aggregate, version: version}
+			pending[aggregateID] = p
+			order = append(order, aggregateID)
+		}
+
+		// Earlier commands for the same aggregate must be reflected in its
+		// state before the next command is applied.
+		for ; p.folded < len(p.events); p.folded++ {
+			if err := r.on(aggregateID, p.aggregate, p.events[p.folded]); err != nil {
+				c.err = err
+				return nil, fmt.Errorf("unable to fold event produced by an earlier command for aggregate %q: %w", aggregateID, err)
+			}
+		}
+
+		// Every command is validated before any records are marshaled so an
+		// invalid event leaves the store untouched.
+		version := p.version + int64(len(p.events))
+		events, err := r.decideOn(commandCtx, aggregateID, p.aggregate, version, command)
+		switch {
+		case errors.Is(err, es.ErrNoEventsProduced):
+		case err != nil:
+			c.err = err
+			return nil, fmt.Errorf("command %d for aggregate %q: %w", i, aggregateID, err)
+		default:
+			c.produced, c.version = len(events), events[len(events)-1].EventVersion()
+			p.events = append(p.events, events...)
+		}
+	}
+
+	streams := make([]es.StreamRecords, 0, len(order))
+	versions = make(map[string]int64, len(order))
+
+	for _, aggregateID := range order {
+		p := pending[aggregateID]
+		if len(p.events) == 0 {
+			continue
+		}
+
+		history, err := r.marshal(ctx, p.events)
+		if err != nil {
+			r.logUnsaved(ctx, "unable to marshal events", aggregateID, p.events, err)
+			return nil, err
+		}
+
+		streams = append(streams, es.StreamRecords{AggregateID: aggregateID, Records: history})
+		versions[aggregateID] = p.events[len(p.events)-1].EventVersion()
+	}
+
+	if len(streams) == 0 {
+		return nil, es.ErrNoEventsProduced
+	}
+
+	start := time.Now()
+	err = store.SaveAll(ctx, streams...)
+	for _, stream := range streams {
+		r.logSave(ctx, stream.AggregateID, pending[stream.AggregateID].events, start, err)
+	}
+
+	if err != nil {
+		for _, stream := range streams {
+			r.cache.remove(stream.AggregateID)
+		}
+		return nil, err
+	}
+
+	for _, stream := range streams {
+		r.publish(ctx, pending[stream.AggregateID].events)
+	}
+
+	return versions, nil
+}
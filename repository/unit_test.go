@@ -0,0 +1,201 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/metrics"
+	"github.com/aarongreenlee/eventsource/repository"
+	"github.com/aarongreenlee/eventsource/store/memory"
+	"github.com/aarongreenlee/eventsource/trace"
+)
+
+// TestUnitOfWork asserts commands for several aggregates are saved together.
+func TestUnitOfWork(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}})
+	require.NoError(t, err)
+
+	versions, err := repo.UnitOfWork().
+		Add(increment("a", 1), increment("b", 2), increment("a", 3)).
+		Commit(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"a": 2, "b": 1}, versions)
+
+	aggregate, err := repo.Load(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 4, aggregate.(*Counter).Value)
+
+	aggregate, err = repo.Load(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, 2, aggregate.(*Counter).Value)
+
+	page, err := repo.Store().(es.Lister).List(ctx, es.ListQuery{AggregateType: "Counter"})
+	require.NoError(t, err)
+	assert.Len(t, page.Aggregates, 2)
+}
+
+// TestWithAggregateType asserts records are listed by the aggregate type
+// given to the repository.
+func TestWithAggregateType(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}},
+		repository.WithAggregateType("billing.Counter"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "billing.Counter", repo.AggregateType())
+
+	_, err = repo.Apply(ctx, increment("a", 1))
+	require.NoError(t, err)
+
+	page, err := repo.Store().(es.Lister).List(ctx, es.ListQuery{AggregateType: "billing.Counter"})
+	require.NoError(t, err)
+	assert.Len(t, page.Aggregates, 1)
+
+	page, err = repo.Store().(es.Lister).List(ctx, es.ListQuery{AggregateType: "Counter"})
+	require.NoError(t, err)
+	assert.Empty(t, page.Aggregates)
+
+	_, err = repository.New(&Counter{}, nil, repository.WithAggregateType(""))
+	assert.Error(t, err)
+}
+
+// TestUnitOfWorkRejected asserts nothing is saved when any command is
+// rejected.
+func TestUnitOfWorkRejected(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}})
+	require.NoError(t, err)
+
+	_, err = repo.UnitOfWork().
+		Add(increment("a", 1), increment("b", -1)).
+		Commit(ctx)
+	require.Error(t, err)
+
+	_, err = repo.Store().Load(ctx, "a", 0, 0)
+	assert.True(t, errors.Is(err, es.ErrNotFound))
+}
+
+// singleStreamStore hides the es.TxStore implementation of the memory store.
+type singleStreamStore struct {
+	es.Store
+}
+
+// TestUnitOfWorkTxUnsupported asserts a unit of work fails before saving when
+// the store cannot save several aggregates atomically.
+func TestUnitOfWorkTxUnsupported(t *testing.T) {
+	ctx := context.Background()
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}},
+		repository.WithStore(singleStreamStore{Store: memory.New()}),
+	)
+	require.NoError(t, err)
+
+	_, err = repo.UnitOfWork().Add(increment("a", 1)).Commit(ctx)
+	assert.True(t, errors.Is(err, es.ErrTxUnsupported))
+
+	_, err = repo.Store().Load(ctx, "a", 0, 0)
+	assert.True(t, errors.Is(err, es.ErrNotFound))
+}
+
+// TestUnitOfWorkInstrumented asserts each command of a unit of work is traced
+// and measured with the outcome of the unit.
+func TestUnitOfWorkInstrumented(t *testing.T) {
+	ctx := context.Background()
+	recorder := metrics.NewMemory()
+	tracer := trace.NewRecorder()
+
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}},
+		repository.WithMetrics(recorder),
+		repository.WithTracer(tracer),
+	)
+	require.NoError(t, err)
+
+	_, err = repo.UnitOfWork().Add(increment("a", 1), increment("b", 2)).Commit(ctx)
+	require.NoError(t, err)
+
+	_, err = repo.UnitOfWork().Add(increment("a", 1), increment("b", -1)).Commit(ctx)
+	require.Error(t, err)
+
+	labels := func(outcome string) []metrics.Label {
+		return []metrics.Label{
+			{Name: metrics.LabelAggregateType, Value: "Counter"},
+			{Name: metrics.LabelCommandType, Value: "increment"},
+			{Name: metrics.LabelOutcome, Value: outcome},
+		}
+	}
+
+	assert.Equal(t, float64(2), recorder.Counter(metrics.ApplyTotal, labels(metrics.OutcomeOK)...))
+	assert.Equal(t, float64(2), recorder.Counter(metrics.ApplyTotal, labels(metrics.OutcomeRejected)...))
+	assert.Equal(t, []float64{1, 1}, recorder.Histogram(metrics.ApplyEvents, labels(metrics.OutcomeOK)...))
+
+	var applied, failed int
+	for _, span := range tracer.Spans() {
+		switch {
+		case span.Name != trace.SpanApply:
+		case span.Err == nil:
+			applied++
+			assert.Equal(t, int64(1), span.Attributes[trace.AttrVersion])
+		default:
+			failed++
+		}
+		assert.False(t, span.Ended.IsZero(), "expected %s to have ended", span.Name)
+	}
+	assert.Equal(t, 2, applied)
+	assert.Equal(t, 2, failed)
+
+	handle := tracer.Find(trace.SpanHandle)
+	require.NotNil(t, handle)
+	require.NotNil(t, handle.Parent)
+	assert.Equal(t, trace.SpanApply, handle.Parent.Name)
+}
+
+// TestUnitOfWorkCommandQueue asserts a unit of work waits for the commands
+// queued for its aggregates rather than conflicting with them.
+func TestUnitOfWorkCommandQueue(t *testing.T) {
+	ctx := context.Background()
+
+	// A slow handler leaves room for commands to interleave.
+	repo, err := repository.New(&Counter{}, []es.Event{&Incremented{}},
+		repository.WithCommandQueue(100, time.Millisecond),
+		repository.WithHandler(func(ctx context.Context, aggregate es.Aggregate, command es.Command) ([]es.Event, error) {
+			time.Sleep(time.Millisecond)
+			return aggregate.(*Counter).Apply(ctx, command)
+		}),
+	)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := repo.Apply(ctx, increment("a", 1))
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := repo.UnitOfWork().Add(increment("b", 1), increment("a", 1)).Commit(ctx)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	aggregate, err := repo.Load(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 40, aggregate.(*Counter).Value)
+
+	aggregate, err = repo.Load(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, 20, aggregate.(*Counter).Value)
+}
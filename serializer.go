@@ -1,5 +1,7 @@
 package eventsource
 
+import "context"
+
 // Serializer implementations should serialize Events so they can be stored.
 // Once serialized, an Event is called a Record.
 type Serializer interface {
@@ -13,3 +15,31 @@ type Serializer interface {
 	// UnmarshalEvent implementations should deserialize a Record into an Event.
 	UnmarshalEvent(record Record) (Event, error)
 }
+
+// ContextSerializer may be implemented by a Serializer which makes use of the
+// context of the operation, for example to trace it.
+type ContextSerializer interface {
+	// MarshalEventContext behaves as Serializer.MarshalEvent.
+	MarshalEventContext(ctx context.Context, event Event) (Record, error)
+
+	// UnmarshalEventContext behaves as Serializer.UnmarshalEvent.
+	UnmarshalEventContext(ctx context.Context, record Record) (Event, error)
+}
+
+// MarshalEvent serializes the event with the context when the serializer
+// implements ContextSerializer.
+func MarshalEvent(ctx context.Context, serializer Serializer, event Event) (Record, error) {
+	if s, ok := serializer.(ContextSerializer); ok {
+		return s.MarshalEventContext(ctx, event)
+	}
+	return serializer.MarshalEvent(event)
+}
+
+// UnmarshalEvent deserializes the record with the context when the
+// serializer implements ContextSerializer.
+func UnmarshalEvent(ctx context.Context, serializer Serializer, record Record) (Event, error) {
+	if s, ok := serializer.(ContextSerializer); ok {
+		return s.UnmarshalEventContext(ctx, record)
+	}
+	return serializer.UnmarshalEvent(record)
+}
@@ -11,18 +11,40 @@ import (
 	es "github.com/aarongreenlee/eventsource"
 )
 
+// format prefixes every record written by MarshalEvent. A gob stream never
+// begins with a zero byte, so records written before the prefix was introduced,
+// which hold a gobEvent, are still recognised and decoded.
+var format = []byte{0, 1}
+
+// gobEvent is the envelope of records written before the format prefix was
+// introduced. Data was resolved through the process-global gob registry.
 type gobEvent struct {
 	Type string
 	Data es.Event
 }
 
 // Serializer de/serializes events which have been bound to the serializer.
+//
+// Each record holds a format prefix followed by the event type and the gob
+// encoding of the event. Event types are resolved through a registry held by
+// each Serializer rather than the process-global gob registry so serializers
+// never conflict with one another, and the Go type bound to an event type may
+// be renamed or moved without affecting stored records. A Serializer is safe
+// for concurrent use.
+//
+// Records written by earlier versions of this package, which lack the format
+// prefix, are decoded using the process-global gob registry. The bound types
+// are registered there under the names gob.Register would give them the first
+// time such a record is read. Re-saving those events migrates them to the
+// current format.
 type Serializer struct {
-	eventTypes map[string]struct{}
-	m          sync.Mutex
+	eventTypes map[string]reflect.Type
+	m          sync.RWMutex
 }
 
-// Bind registers the specified events with the serializer.
+// Bind registers the specified events with the serializer by their event
+// type. Binding an event type to a second Go type returns an error. The form
+// bound, value or pointer, is the form returned by UnmarshalEvent.
 // Bind may be called multiple times.
 func (s *Serializer) Bind(events ...es.Event) error {
 	s.m.Lock()
@@ -30,33 +52,62 @@ func (s *Serializer) Bind(events ...es.Event) error {
 
 	// Allow calls to Bind to establish the event registry.
 	if s.eventTypes == nil {
-		s.eventTypes = make(map[string]struct{}, len(events))
+		s.eventTypes = make(map[string]reflect.Type, len(events))
 	}
 
 	for _, event := range events {
-		gob.Register(event)
-		eventType := event.EventType()
+		if event == nil {
+			return errors.New("unable to bind a nil event")
+		}
 
+		eventType := event.EventType()
 		if eventType == "" {
 			return errors.New("unable to determine event type")
 		}
 
-		s.eventTypes[eventType] = struct{}{}
+		t := reflect.TypeOf(event)
+		if existing, ok := s.eventTypes[eventType]; ok {
+			if base(existing) != base(t) {
+				return fmt.Errorf("event type %q is already bound to %s and can not be bound to %s", eventType, existing, t)
+			}
+			continue
+		}
+
+		s.eventTypes[eventType] = t
 	}
 
 	return nil
 }
 
-// MarshalEvent marshals the event into a Record which can be stored.
+// MarshalEvent marshals the event into a Record which can be stored. The
+// event type must have been bound to the Go type of the event, in either its
+// value or pointer form; an error wrapping es.ErrUnboundEventType is returned
+// otherwise.
 func (s *Serializer) MarshalEvent(v es.Event) (es.Record, error) {
-	var buffer bytes.Buffer
-	err := gob.NewEncoder(&buffer).Encode(gobEvent{
-		Type: v.EventType(),
-		Data: v,
-	})
+	if v == nil {
+		return es.Record{}, errors.New("unable to encode a nil event")
+	}
 
-	if err != nil {
-		return es.Record{}, fmt.Errorf("unable to encode event: %w", err)
+	eventType := v.EventType()
+
+	s.m.RLock()
+	t, ok := s.eventTypes[eventType]
+	s.m.RUnlock()
+
+	switch {
+	case !ok:
+		return es.Record{}, fmt.Errorf("%w: %q", es.ErrUnboundEventType, eventType)
+	case base(t) != base(reflect.TypeOf(v)):
+		return es.Record{}, fmt.Errorf("%w: %q is bound to %s not %T", es.ErrUnboundEventType, eventType, t, v)
+	}
+
+	buffer := bytes.NewBuffer(append([]byte(nil), format...))
+	encoder := gob.NewEncoder(buffer)
+
+	for _, value := range []interface{}{eventType, v} {
+		if err := encoder.Encode(value); err != nil {
+			return es.Record{}, fmt.Errorf("unable to encode event: %w", err)
+		}
 	}
 
 	return es.Record{
@@ -67,27 +118,82 @@ func (s *Serializer) MarshalEvent(v es.Event) (es.Record, error) {
 
 // UnmarshalEvent converts the persistent type, Record, into an Event instance
 func (s *Serializer) UnmarshalEvent(record es.Record) (es.Event, error) {
-	event := gobEvent{}
+	if !bytes.HasPrefix(record.Data, format) {
+		return s.unmarshalLegacy(record)
+	}
 
-	err := gob.NewDecoder(bytes.NewReader(record.Data)).Decode(&event)
-	if err != nil {
+	decoder := gob.NewDecoder(bytes.NewReader(record.Data[len(format):]))
+
+	var eventType string
+	if err := decoder.Decode(&eventType); err != nil {
 		return nil, fmt.Errorf("unable to unmarshal event: %w", err)
 	}
 
-	_, ok := s.eventTypes[event.Type]
+	s.m.RLock()
+	t, ok := s.eventTypes[eventType]
+	s.m.RUnlock()
+
 	if !ok {
-		return nil, fmt.Errorf("unbound event type: %q", event.Type)
+		return nil, fmt.Errorf("%w: %q", es.ErrUnboundEventType, eventType)
+	}
+
+	ptr := reflect.New(base(t))
+	if err := decoder.Decode(ptr.Interface()); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal event: %w", err)
+	}
+
+	v := ptr
+	if t.Kind() != reflect.Ptr {
+		v = ptr.Elem()
 	}
 
 	// Sanity check for Event type casting.
-	eventData, ok := event.Data.(es.Event)
+	eventData, ok := v.Interface().(es.Event)
 	if !ok {
-		return nil, fmt.Errorf("unable to cast to Event due to unknown data type %q", reflect.TypeOf(event.Data).Name())
+		return nil, fmt.Errorf("unable to cast to Event due to unknown data type %q", t)
 	}
 
 	return eventData, nil
 }
 
+// unmarshalLegacy decodes a record holding a gobEvent.
+func (s *Serializer) unmarshalLegacy(record es.Record) (es.Event, error) {
+	s.m.RLock()
+	types := make([]reflect.Type, 0, len(s.eventTypes))
+	for _, t := range s.eventTypes {
+		types = append(types, t)
+	}
+	s.m.RUnlock()
+
+	for _, t := range types {
+		registerLegacy(t)
+	}
+
+	event := gobEvent{}
+
+	err := gob.NewDecoder(bytes.NewReader(record.Data)).Decode(&event)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal event: %w", err)
+	}
+
+	if event.Data == nil {
+		return nil, errors.New("unable to unmarshal event: record holds no event")
+	}
+
+	s.m.RLock()
+	t, ok := s.eventTypes[event.Type]
+	s.m.RUnlock()
+
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("%w: %q", es.ErrUnboundEventType, event.Type)
+	case base(t) != base(reflect.TypeOf(event.Data)):
+		return nil, fmt.Errorf("%w: %q is bound to %s not %T", es.ErrUnboundEventType, event.Type, t, event.Data)
+	}
+
+	return event.Data, nil
+}
+
 // MarshalAll is a utility that marshals all the events provided into a History object
 func (s *Serializer) MarshalAll(events ...es.Event) (es.History, error) {
 	history := make(es.History, 0, len(events))
@@ -107,7 +213,7 @@ func (s *Serializer) MarshalAll(events ...es.Event) (es.History, error) {
 // specified events. Bind may be subsequently called to add more events.
 func New(events ...es.Event) (*Serializer, error) {
 	serializer := &Serializer{
-		eventTypes: make(map[string]struct{}),
+		eventTypes: make(map[string]reflect.Type),
 	}
 
 	if err := serializer.Bind(events...); err != nil {
@@ -116,3 +222,33 @@ func New(events ...es.Event) (*Serializer, error) {
 
 	return serializer, nil
 }
+
+// base removes a single level of pointer indirection.
+func base(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+var (
+	legacyMu    sync.Mutex
+	legacyTypes = map[reflect.Type]struct{}{}
+)
+
+// registerLegacy registers the bound form of t with the process-global gob
+// registry, as the Bind of earlier versions did, so records holding a gobEvent
+// can be decoded. A name already registered to another type is left as it is;
+// records using it will fail to decode rather than panic.
+func registerLegacy(t reflect.Type) {
+	legacyMu.Lock()
+	defer legacyMu.Unlock()
+
+	if _, ok := legacyTypes[t]; ok {
+		return
+	}
+	legacyTypes[t] = struct{}{}
+
+	defer func() { _ = recover() }()
+	gob.Register(reflect.New(t).Elem().Interface())
+}
@@ -1,6 +1,9 @@
 package gob_test
 
 import (
+	"bytes"
+	stdgob "encoding/gob"
+	"errors"
 	"testing"
 	"time"
 
@@ -8,6 +11,7 @@ import (
 
 	es "github.com/aarongreenlee/eventsource"
 	"github.com/aarongreenlee/eventsource/serializer/gob"
+	"github.com/aarongreenlee/eventsource/serializer/serializertest"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -46,7 +50,7 @@ func TestGobSerializer(t *testing.T) {
 			Event: Event{
 				ID:      "2",
 				Version: 2,
-				Type:    eventAType,
+				Type:    eventBType,
 			},
 			Description: "An event which is tested",
 		},
@@ -167,3 +171,130 @@ func (e Event) EventAt() time.Time {
 func (e Event) EventType() string {
 	return e.Type
 }
+
+// TestBindConflict asserts binding an event type to a second Go type returns
+// an error rather than panicking.
+func TestBindConflict(t *testing.T) {
+	serializer, err := gob.New(&EventA{Event: Event{Type: eventAType}})
+	require.NoError(t, err)
+
+	err = serializer.Bind(&EventB{Event: Event{Type: eventAType}})
+	assert.Error(t, err)
+
+	// The value and pointer forms of a bound type do not conflict.
+	assert.NoError(t, serializer.Bind(EventA{Event: Event{Type: eventAType}}))
+}
+
+// TestIsolatedRegistries asserts serializers do not share bindings.
+func TestIsolatedRegistries(t *testing.T) {
+	pointers, err := gob.New(&EventA{Event: Event{Type: eventAType}})
+	require.NoError(t, err)
+
+	values, err := gob.New(EventA{Event: Event{Type: eventAType}})
+	require.NoError(t, err)
+
+	event := EventA{Event: Event{ID: "1", Version: 1, Type: eventAType}, Name: "Alpha"}
+
+	record, err := pointers.MarshalEvent(event)
+	require.NoError(t, err)
+
+	v, err := pointers.UnmarshalEvent(record)
+	require.NoError(t, err)
+	assert.Equal(t, &event, v)
+
+	v, err = values.UnmarshalEvent(record)
+	require.NoError(t, err)
+	assert.Equal(t, event, v)
+
+	_, err = (&gob.Serializer{}).UnmarshalEvent(record)
+	assert.True(t, errors.Is(err, es.ErrUnboundEventType))
+}
+
+// TestUnboundType asserts events are rejected when marshaled unless their
+// event type is bound to their Go type.
+func TestUnboundType(t *testing.T) {
+	serializer, err := gob.New(&EventA{Event: Event{Type: eventAType}})
+	require.NoError(t, err)
+
+	// The event type is not bound.
+	_, err = serializer.MarshalEvent(EventA{Event: Event{ID: "1", Version: 1, Type: eventBType}})
+	assert.True(t, errors.Is(err, es.ErrUnboundEventType))
+
+	// The event type is bound to a different Go type.
+	_, err = serializer.MarshalEvent(EventB{Event: Event{ID: "1", Version: 1, Type: eventAType}})
+	assert.True(t, errors.Is(err, es.ErrUnboundEventType))
+}
+
+// RenamedA is EventA after its Go type was renamed.
+type RenamedA struct {
+	Name string
+	Event
+}
+
+// TestRenamedType asserts records are decoded by their event type so the Go
+// type bound to it may be renamed after records were stored.
+func TestRenamedType(t *testing.T) {
+	writer, err := gob.New(&EventA{Event: Event{Type: eventAType}})
+	require.NoError(t, err)
+
+	record, err := writer.MarshalEvent(EventA{Event: Event{ID: "1", Version: 1, Type: eventAType}, Name: "Alpha"})
+	require.NoError(t, err)
+
+	reader, err := gob.New(&RenamedA{Event: Event{Type: eventAType}})
+	require.NoError(t, err)
+
+	v, err := reader.UnmarshalEvent(record)
+	require.NoError(t, err)
+	assert.Equal(t, &RenamedA{Event: Event{ID: "1", Version: 1, Type: eventAType}, Name: "Alpha"}, v)
+}
+
+// legacyEvent mirrors the envelope written by earlier versions of the
+// serializer.
+type legacyEvent struct {
+	Type string
+	Data es.Event
+}
+
+// TestLegacyRecord asserts records written before the format prefix was
+// introduced can still be read.
+func TestLegacyRecord(t *testing.T) {
+	event := &EventB{
+		Event:       Event{ID: "1", Version: 1, Type: eventBType},
+		Description: "Written by an earlier version",
+	}
+
+	stdgob.Register(&EventB{})
+
+	var buffer bytes.Buffer
+	require.NoError(t, stdgob.NewEncoder(&buffer).Encode(legacyEvent{Type: event.Type, Data: event}))
+	record := es.Record{Version: 1, Data: buffer.Bytes()}
+
+	serializer, err := gob.New(&EventA{Event: Event{Type: eventAType}}, &EventB{Event: Event{Type: eventBType}})
+	require.NoError(t, err)
+
+	v, err := serializer.UnmarshalEvent(record)
+	require.NoError(t, err)
+	assert.Equal(t, event, v)
+
+	// Re-saving the event migrates it to the current format.
+	migrated, err := serializer.MarshalEvent(v)
+	require.NoError(t, err)
+	v, err = serializer.UnmarshalEvent(migrated)
+	require.NoError(t, err)
+	assert.Equal(t, event, v)
+
+	_, err = (&gob.Serializer{}).UnmarshalEvent(record)
+	assert.True(t, errors.Is(err, es.ErrUnboundEventType))
+}
+
+func TestConformance(t *testing.T) {
+	serializertest.Run(t, func() es.Serializer {
+		return &gob.Serializer{}
+	}, &EventA{
+		Event: Event{ID: "1", Version: 1, At: time.Date(2019, 9, 16, 12, 0, 0, 0, time.UTC), Type: eventAType},
+		Name:  "Alpha",
+	}, &EventB{
+		Event:       Event{ID: "1", Version: 2, At: time.Date(2019, 9, 16, 12, 0, 0, 0, time.UTC), Type: eventBType},
+		Description: "An event which is tested",
+	})
+}
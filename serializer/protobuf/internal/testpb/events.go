@@ -0,0 +1,36 @@
+package testpb
+
+import "time"
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative events.proto
+
+// AggregateID implements the eventsource.Event interface.
+func (e *Opened) AggregateID() string { return e.GetId() }
+
+// EventVersion implements the eventsource.Event interface.
+func (e *Opened) EventVersion() int64 { return e.GetVersion() }
+
+// EventAt implements the eventsource.Event interface.
+func (e *Opened) EventAt() time.Time { return unixNano(e.GetAtUnixNano()) }
+
+// EventType implements the eventsource.Event interface.
+func (e *Opened) EventType() string { return "opened" }
+
+// AggregateID implements the eventsource.Event interface.
+func (e *Deposited) AggregateID() string { return e.GetId() }
+
+// EventVersion implements the eventsource.Event interface.
+func (e *Deposited) EventVersion() int64 { return e.GetVersion() }
+
+// EventAt implements the eventsource.Event interface.
+func (e *Deposited) EventAt() time.Time { return unixNano(e.GetAtUnixNano()) }
+
+// EventType implements the eventsource.Event interface.
+func (e *Deposited) EventType() string { return "deposited" }
+
+func unixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
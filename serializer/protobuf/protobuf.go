@@ -0,0 +1,170 @@
+// Package protobuf implements an eventsource.Serializer for events which are
+// Protocol Buffer messages. Each record holds a google.protobuf.Any envelope
+// so consumers written in other languages can identify and decode events
+// using the message schema.
+package protobuf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	es "github.com/aarongreenlee/eventsource"
+)
+
+// TypeURLPrefix prefixes the full message name to form the type URL of the
+// envelope.
+const TypeURLPrefix = "type.googleapis.com/"
+
+// Event is implemented by events which may be bound to the Serializer.
+type Event interface {
+	es.Event
+	proto.Message
+}
+
+// binding associates an event type with the message type which carries it.
+type binding struct {
+	eventType   string
+	typeURL     string
+	messageType protoreflect.MessageType
+}
+
+// Serializer de/serializes Protocol Buffer events which have been bound to the
+// serializer. Bindings are held by each Serializer rather than the global
+// Protocol Buffer registry.
+type Serializer struct {
+	byEventType map[string]binding
+	byTypeURL   map[string]binding
+	m           sync.RWMutex
+}
+
+// Bind registers the specified events with the serializer. Each event must
+// implement proto.Message and each event type must be carried by exactly one
+// message type. Bind may be called multiple times.
+func (s *Serializer) Bind(events ...es.Event) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	// Allow calls to Bind to establish the event registry.
+	if s.byEventType == nil {
+		s.byEventType = make(map[string]binding, len(events))
+		s.byTypeURL = make(map[string]binding, len(events))
+	}
+
+	for _, event := range events {
+		if event == nil {
+			return errors.New("unable to bind a nil event")
+		}
+
+		eventType := event.EventType()
+		if eventType == "" {
+			return fmt.Errorf("unable to determine event type of %T", event)
+		}
+
+		message, ok := event.(proto.Message)
+		if !ok {
+			return fmt.Errorf("event %T does not implement proto.Message", event)
+		}
+
+		messageType := message.ProtoReflect().Type()
+		b := binding{
+			eventType:   eventType,
+			typeURL:     TypeURLPrefix + string(messageType.Descriptor().FullName()),
+			messageType: messageType,
+		}
+
+		if existing, ok := s.byEventType[eventType]; ok && existing.typeURL != b.typeURL {
+			return fmt.Errorf("event type %q is already bound to %s", eventType, existing.typeURL)
+		}
+		if existing, ok := s.byTypeURL[b.typeURL]; ok && existing.eventType != eventType {
+			return fmt.Errorf("%s is already bound to event type %q", b.typeURL, existing.eventType)
+		}
+
+		s.byEventType[eventType] = b
+		s.byTypeURL[b.typeURL] = b
+	}
+
+	return nil
+}
+
+// MarshalEvent marshals the event into a Record which can be stored.
+func (s *Serializer) MarshalEvent(v es.Event) (es.Record, error) {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return es.Record{}, fmt.Errorf("event %T does not implement proto.Message", v)
+	}
+
+	s.m.RLock()
+	b, ok := s.byEventType[v.EventType()]
+	s.m.RUnlock()
+
+	if !ok {
+		return es.Record{}, fmt.Errorf("%w: %q", es.ErrUnboundEventType, v.EventType())
+	}
+
+	if name := message.ProtoReflect().Descriptor().FullName(); TypeURLPrefix+string(name) != b.typeURL {
+		return es.Record{}, fmt.Errorf("event type %q is bound to %s not %s", b.eventType, b.typeURL, name)
+	}
+
+	value, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+	if err != nil {
+		return es.Record{}, fmt.Errorf("unable to encode event: %w", err)
+	}
+
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(&anypb.Any{
+		TypeUrl: b.typeURL,
+		Value:   value,
+	})
+	if err != nil {
+		return es.Record{}, fmt.Errorf("unable to encode event envelope: %w", err)
+	}
+
+	return es.Record{
+		Version: v.EventVersion(),
+		Data:    data,
+	}, nil
+}
+
+// UnmarshalEvent converts the persistent type, Record, into an Event instance
+func (s *Serializer) UnmarshalEvent(record es.Record) (es.Event, error) {
+	envelope := &anypb.Any{}
+	if err := proto.Unmarshal(record.Data, envelope); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal event envelope: %w", err)
+	}
+
+	s.m.RLock()
+	b, ok := s.byTypeURL[envelope.GetTypeUrl()]
+	s.m.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", es.ErrUnboundEventType, envelope.GetTypeUrl())
+	}
+
+	message := b.messageType.New().Interface()
+	if err := proto.Unmarshal(envelope.GetValue(), message); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal event: %w", err)
+	}
+
+	event, ok := message.(es.Event)
+	if !ok {
+		return nil, fmt.Errorf("unable to cast %T to Event", message)
+	}
+
+	return event, nil
+}
+
+// New constructs a new Protocol Buffer serializer and populates it with the
+// specified events. Bind may be subsequently called to add more events.
+func New(events ...es.Event) (*Serializer, error) {
+	serializer := &Serializer{}
+
+	if err := serializer.Bind(events...); err != nil {
+		return nil, fmt.Errorf("failed to bind events to serializer: %w", err)
+	}
+
+	return serializer, nil
+}
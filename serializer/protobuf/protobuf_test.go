@@ -0,0 +1,83 @@
+package protobuf_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/serializer/protobuf"
+	"github.com/aarongreenlee/eventsource/serializer/protobuf/internal/testpb"
+	"github.com/aarongreenlee/eventsource/serializer/serializertest"
+)
+
+func events() []es.Event {
+	at := time.Date(2019, 9, 16, 12, 0, 0, 0, time.UTC).UnixNano()
+
+	return []es.Event{
+		&testpb.Opened{Id: "1", Version: 1, AtUnixNano: at, Owner: "Big Bird"},
+		&testpb.Deposited{Id: "1", Version: 2, AtUnixNano: at, Amount: 42, Labels: map[string]string{"source": "atm"}},
+	}
+}
+
+func TestConformance(t *testing.T) {
+	serializertest.RunEqual(t, func() es.Serializer {
+		return &protobuf.Serializer{}
+	}, equal, events()...)
+}
+
+// equal compares the messages with proto.Equal.
+func equal(expected, found es.Event) bool {
+	a, ok := expected.(proto.Message)
+	if !ok {
+		return false
+	}
+	b, ok := found.(proto.Message)
+	return ok && proto.Equal(a, b)
+}
+
+// TestEnvelope asserts records carry the type URL of the message so they can
+// be decoded without this package.
+func TestEnvelope(t *testing.T) {
+	serializer, err := protobuf.New(events()...)
+	require.NoError(t, err)
+
+	record, err := serializer.MarshalEvent(events()[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), record.Version)
+
+	envelope := &anypb.Any{}
+	require.NoError(t, proto.Unmarshal(record.Data, envelope))
+	assert.Equal(t, "type.googleapis.com/eventsource.testpb.Opened", envelope.GetTypeUrl())
+
+	opened := &testpb.Opened{}
+	require.NoError(t, envelope.UnmarshalTo(opened))
+	assert.Equal(t, "Big Bird", opened.GetOwner())
+}
+
+// plainEvent is an Event which is not a Protocol Buffer message.
+type plainEvent struct {
+	es.EventModel
+}
+
+func (plainEvent) EventType() string { return "plain" }
+
+// TestBindRejectsNonMessages asserts only Protocol Buffer messages are bound.
+func TestBindRejectsNonMessages(t *testing.T) {
+	_, err := protobuf.New(plainEvent{})
+	assert.Error(t, err)
+}
+
+// TestMarshalUnbound asserts events must be bound before being marshaled.
+func TestMarshalUnbound(t *testing.T) {
+	serializer, err := protobuf.New(events()[1])
+	require.NoError(t, err)
+
+	_, err = serializer.MarshalEvent(events()[0])
+	assert.True(t, errors.Is(err, es.ErrUnboundEventType))
+}
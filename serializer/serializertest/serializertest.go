@@ -0,0 +1,254 @@
+// Package serializertest provides a conformance suite which every
+// eventsource.Serializer implementation is expected to pass.
+//
+//	func TestConformance(t *testing.T) {
+//		serializertest.Run(t, func() es.Serializer {
+//			return &gob.Serializer{}
+//		}, &EventA{...}, &EventB{...})
+//	}
+package serializertest
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	es "github.com/aarongreenlee/eventsource"
+)
+
+// Run exercises a Serializer. The factory must return a new serializer with
+// no events bound on every call. The events are populated samples of at least
+// two distinct event types; they are bound to the serializers under test.
+//
+// The suite verifies:
+//
+//   - events survive a round-trip through MarshalEvent and UnmarshalEvent
+//   - records of unbound event types fail with es.ErrUnboundEventType
+//   - Bind rejects events with an empty event type
+//   - Bind rejects a nil event
+//   - records carry the event version and event metadata is preserved
+//   - Bind, MarshalEvent and UnmarshalEvent may be called concurrently
+//
+// Round-tripped events are compared with Equal; use RunEqual when events,
+// such as Protocol Buffer messages, need a different comparison.
+func Run(t *testing.T, factory func() es.Serializer, events ...es.Event) {
+	t.Helper()
+
+	RunEqual(t, factory, Equal, events...)
+}
+
+// RunEqual is Run with round-tripped events compared by equal.
+func RunEqual(t *testing.T, factory func() es.Serializer, equal func(expected, found es.Event) bool, events ...es.Event) {
+	t.Helper()
+
+	if len(events) < 2 {
+		t.Fatalf("serializertest requires events of at least two event types")
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		testRoundTrip(t, factory, equal, events)
+	})
+	t.Run("UnboundEventType", func(t *testing.T) {
+		testUnboundEventType(t, factory, events)
+	})
+	t.Run("EmptyEventType", func(t *testing.T) {
+		testEmptyEventType(t, factory, events)
+	})
+	t.Run("NilEvent", func(t *testing.T) {
+		testNilEvent(t, factory)
+	})
+	t.Run("Metadata", func(t *testing.T) {
+		testMetadata(t, factory, events)
+	})
+	t.Run("Concurrency", func(t *testing.T) {
+		testConcurrency(t, factory, events)
+	})
+}
+
+// testRoundTrip asserts every event unmarshals to an equal event.
+func testRoundTrip(t *testing.T, factory func() es.Serializer, equal func(expected, found es.Event) bool, events []es.Event) {
+	serializer := factory()
+	if err := serializer.Bind(events...); err != nil {
+		t.Fatalf("unable to bind events: %s", err)
+	}
+
+	for _, event := range events {
+		record, err := serializer.MarshalEvent(event)
+		if err != nil {
+			t.Fatalf("unable to marshal %T: %s", event, err)
+		}
+
+		decoded, err := serializer.UnmarshalEvent(record)
+		if err != nil {
+			t.Fatalf("unable to unmarshal %T: %s", event, err)
+		}
+
+		if !equal(event, decoded) {
+			t.Errorf("round-trip of %T produced %T which is not equal:\n\texpected: %+v\n\tfound:    %+v", event, decoded, event, decoded)
+		}
+	}
+}
+
+// testUnboundEventType asserts records of event types which have not been
+// bound are rejected with es.ErrUnboundEventType.
+func testUnboundEventType(t *testing.T, factory func() es.Serializer, events []es.Event) {
+	writer := factory()
+	if err := writer.Bind(events...); err != nil {
+		t.Fatalf("unable to bind events: %s", err)
+	}
+
+	record, err := writer.MarshalEvent(events[0])
+	if err != nil {
+		t.Fatalf("unable to marshal %T: %s", events[0], err)
+	}
+
+	reader := factory()
+	for _, event := range events[1:] {
+		if event.EventType() == events[0].EventType() {
+			continue
+		}
+		if err := reader.Bind(event); err != nil {
+			t.Fatalf("unable to bind events: %s", err)
+		}
+	}
+
+	_, err = reader.UnmarshalEvent(record)
+	if !errors.Is(err, es.ErrUnboundEventType) {
+		t.Fatalf("expected %q but found %v", es.ErrUnboundEventType, err)
+	}
+}
+
+// untyped wraps an event to report an empty event type.
+type untyped struct {
+	es.Event
+}
+
+// EventType implements the eventsource.Event interface.
+func (untyped) EventType() string {
+	return ""
+}
+
+// testEmptyEventType asserts Bind rejects events without an event type.
+func testEmptyEventType(t *testing.T, factory func() es.Serializer, events []es.Event) {
+	serializer := factory()
+	if err := serializer.Bind(untyped{Event: events[0]}); err == nil {
+		t.Fatalf("expected Bind to reject an event with an empty event type")
+	}
+}
+
+// testNilEvent asserts Bind returns an error, rather than panicking, when
+// given a nil event.
+func testNilEvent(t *testing.T, factory func() es.Serializer) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("expected Bind to reject a nil event but it panicked: %v", r)
+		}
+	}()
+
+	if err := factory().Bind(nil); err == nil {
+		t.Fatalf("expected Bind to reject a nil event")
+	}
+}
+
+// testMetadata asserts records carry the event version and the aggregate id,
+// version, type and timestamp of each event survive a round-trip.
+func testMetadata(t *testing.T, factory func() es.Serializer, events []es.Event) {
+	serializer := factory()
+	if err := serializer.Bind(events...); err != nil {
+		t.Fatalf("unable to bind events: %s", err)
+	}
+
+	for _, event := range events {
+		record, err := serializer.MarshalEvent(event)
+		if err != nil {
+			t.Fatalf("unable to marshal %T: %s", event, err)
+		}
+
+		if record.Version != event.EventVersion() {
+			t.Errorf("expected record version %d but found %d", event.EventVersion(), record.Version)
+		}
+
+		decoded, err := serializer.UnmarshalEvent(record)
+		if err != nil {
+			t.Fatalf("unable to unmarshal %T: %s", event, err)
+		}
+
+		switch {
+		case decoded.AggregateID() != event.AggregateID():
+			t.Errorf("expected aggregate id %q but found %q", event.AggregateID(), decoded.AggregateID())
+		case decoded.EventVersion() != event.EventVersion():
+			t.Errorf("expected event version %d but found %d", event.EventVersion(), decoded.EventVersion())
+		case decoded.EventType() != event.EventType():
+			t.Errorf("expected event type %q but found %q", event.EventType(), decoded.EventType())
+		case !decoded.EventAt().Equal(event.EventAt()):
+			t.Errorf("expected event timestamp %s but found %s", event.EventAt(), decoded.EventAt())
+		}
+	}
+}
+
+// testConcurrency asserts events may be bound while other goroutines marshal
+// and unmarshal events. Run the suite with the race detector enabled.
+func testConcurrency(t *testing.T, factory func() es.Serializer, events []es.Event) {
+	serializer := factory()
+	if err := serializer.Bind(events[0]); err != nil {
+		t.Fatalf("unable to bind events: %s", err)
+	}
+
+	record, err := serializer.MarshalEvent(events[0])
+	if err != nil {
+		t.Fatalf("unable to marshal %T: %s", events[0], err)
+	}
+
+	const workers = 8
+	const iterations = 50
+
+	var wg sync.WaitGroup
+	failures := make(chan error, workers*iterations*2)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				if err := serializer.Bind(events...); err != nil {
+					failures <- err
+				}
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				if _, err := serializer.MarshalEvent(events[0]); err != nil {
+					failures <- err
+				}
+				if _, err := serializer.UnmarshalEvent(record); err != nil {
+					failures <- err
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(failures)
+
+	for err := range failures {
+		t.Fatalf("unexpected error during concurrent use: %s", err)
+	}
+}
+
+// Equal reports whether two events are deeply equal ignoring whether either
+// is a pointer.
+func Equal(expected, found es.Event) bool {
+	return reflect.DeepEqual(indirect(expected), indirect(found))
+}
+
+func indirect(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	return rv.Interface()
+}
@@ -2,6 +2,7 @@ package eventsource
 
 import (
 	"context"
+	"sort"
 )
 
 // Store provides an abstraction for the Repository to save data
@@ -15,10 +16,137 @@ type Store interface {
 	Load(ctx context.Context, aggregateID string, fromVersion, toVersion int64) (History, error)
 }
 
+// TxStore may be implemented by a Store which is able to persist records for
+// several aggregates within a single atomic operation.
+type TxStore interface {
+	// SaveAll implementations should persist the records of every stream or,
+	// if any stream fails, none of them.
+	SaveAll(ctx context.Context, streams ...StreamRecords) error
+}
+
+// StreamRecords pairs the records to be saved with the aggregate they belong
+// to.
+type StreamRecords struct {
+	AggregateID string
+	Records     History
+}
+
+// Streamer may be implemented by a Store which is able to read history one
+// record at a time rather than materializing the entire History.
+type Streamer interface {
+	// Stream implementations should return a Cursor over the records stored
+	// within the version range. The range follows the same rules as
+	// Store.Load.
+	Stream(ctx context.Context, aggregateID string, fromVersion, toVersion int64) (Cursor, error)
+}
+
+// Cursor iterates over records in version order. A Cursor must be closed once
+// the caller is finished, including when iteration is stopped early.
+//
+//	cursor, err := eventsource.Stream(ctx, store, id, 0, 0)
+//	if err != nil {
+//		return err
+//	}
+//	defer cursor.Close()
+//
+//	for cursor.Next() {
+//		record := cursor.Record()
+//	}
+//
+//	return cursor.Err()
+type Cursor interface {
+	// Next advances the cursor and reports whether a record is available. Next
+	// returns false when the records are exhausted, the context used to open
+	// the cursor is done or an error occurred.
+	Next() bool
+
+	// Record returns the record the cursor currently points to.
+	Record() Record
+
+	// Err returns the error, if any, which stopped the iteration.
+	Err() error
+
+	// Close releases any resources held by the cursor.
+	Close() error
+}
+
+// Stream opens a Cursor over the history of an aggregate. Stores which
+// implement Streamer are read incrementally; all other stores are read with
+// Store.Load and the resulting History is adapted into a Cursor.
+func Stream(ctx context.Context, store Store, aggregateID string, fromVersion, toVersion int64) (Cursor, error) {
+	if s, ok := store.(Streamer); ok {
+		return s.Stream(ctx, aggregateID, fromVersion, toVersion)
+	}
+
+	history, err := store.Load(ctx, aggregateID, fromVersion, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewHistoryCursor(ctx, history), nil
+}
+
+// NewHistoryCursor adapts a History into a Cursor. Iteration stops once the
+// context is done.
+func NewHistoryCursor(ctx context.Context, history History) Cursor {
+	return &historyCursor{
+		ctx:     ctx,
+		history: history,
+		index:   -1,
+	}
+}
+
+// historyCursor implements Cursor over an in-memory History.
+type historyCursor struct {
+	ctx     context.Context
+	history History
+	index   int
+	err     error
+}
+
+// Next implements the Cursor interface.
+func (c *historyCursor) Next() bool {
+	if c.err != nil || c.history == nil {
+		return false
+	}
+
+	if err := c.ctx.Err(); err != nil {
+		c.err = err
+		return false
+	}
+
+	c.index++
+	return c.index < len(c.history)
+}
+
+// Record implements the Cursor interface.
+func (c *historyCursor) Record() Record {
+	if c.index < 0 || c.index >= len(c.history) {
+		return Record{}
+	}
+	return c.history[c.index]
+}
+
+// Err implements the Cursor interface.
+func (c *historyCursor) Err() error {
+	return c.err
+}
+
+// Close implements the Cursor interface.
+func (c *historyCursor) Close() error {
+	c.history = nil
+	return nil
+}
+
 // Record is a serialized event suitable for storage.
 type Record struct {
 	Data    []byte
 	Version int64
+
+	// AggregateType is set by the repository so stores implementing Lister
+	// can filter by it. The memory, bolt and sql stores persist it and return
+	// it from Load and Stream; other stores may discard it.
+	AggregateType string
 }
 
 // History is a chain of events for a specific resource. Left-folding over
@@ -39,3 +167,25 @@ func (h History) Swap(a, b int) {
 func (h History) Less(a, b int) bool {
 	return h[a].Version < h[b].Version
 }
+
+// Between returns the sub-slice of a sorted History within the version range.
+// The range follows the same rules as Store.Load. The returned History shares
+// the underlying array with h.
+func (h History) Between(fromVersion, toVersion int64) History {
+	start := sort.Search(len(h), func(i int) bool {
+		return h[i].Version >= fromVersion
+	})
+
+	end := len(h)
+	if toVersion != 0 {
+		end = sort.Search(len(h), func(i int) bool {
+			return h[i].Version > toVersion
+		})
+	}
+
+	if start >= end {
+		return History{}
+	}
+
+	return h[start:end:end]
+}
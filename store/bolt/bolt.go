@@ -0,0 +1,356 @@
+// Package bolt implements an eventsource.Store over an embedded bbolt
+// B+tree key-value file for single node deployments which need durable
+// storage without running a database server.
+//
+// Each aggregate is stored in its own bucket, nested within the streams
+// bucket, keyed by the zero padded version of each record so keys sort in
+// version order. Every record saved is also appended to the sequence bucket,
+// keyed by a global sequence number, to preserve the order records were
+// saved across all aggregates.
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+
+	es "github.com/aarongreenlee/eventsource"
+)
+
+var (
+	streamsBucket  = []byte("streams")
+	sequenceBucket = []byte("sequence")
+)
+
+// DefaultBatchSize is the number of records a Cursor reads per transaction
+// unless WithBatchSize is provided.
+const DefaultBatchSize = 256
+
+// Store persists records to a bbolt file.
+type Store struct {
+	db        *bbolt.DB
+	batchSize int
+	timeout   time.Duration
+}
+
+// Option provides functional configuration for a *Store
+type Option func(*Store) error
+
+// WithBatchSize sets the number of records a Cursor reads per transaction.
+func WithBatchSize(size int) Option {
+	return func(s *Store) error {
+		if size < 1 {
+			return errors.New("batch size must be greater than 0")
+		}
+		s.batchSize = size
+		return nil
+	}
+}
+
+// WithTimeout sets how long Open waits for the file lock held by another
+// process. Open waits indefinitely by default.
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *Store) error {
+		s.timeout = timeout
+		return nil
+	}
+}
+
+// Open opens, creating if necessary, the store at path. The store must be
+// closed to release the file.
+func Open(path string, opts ...Option) (*Store, error) {
+	s := &Store{batchSize: DefaultBatchSize}
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, fmt.Errorf("error applying option: %w", err)
+		}
+	}
+
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: s.timeout})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{streamsBucket, sequenceBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("unable to initialize %s: %w", path, err)
+	}
+
+	s.db = db
+
+	return s, nil
+}
+
+// Close releases the file.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Save persists the records within a single transaction. The records must
+// continue from the last version stored; es.ErrConflict is returned if any
+// version has already been stored.
+func (s *Store) Save(ctx context.Context, aggregateID string, records ...es.Record) error {
+	return s.SaveAll(ctx, es.StreamRecords{AggregateID: aggregateID, Records: records})
+}
+
+// SaveAll persists the records of every stream within a single transaction.
+func (s *Store) SaveAll(ctx context.Context, streams ...es.StreamRecords) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		sequence := tx.Bucket(sequenceBucket)
+
+		for _, stream := range streams {
+			if len(stream.Records) == 0 {
+				continue
+			}
+
+			if stream.AggregateID == "" {
+				return errors.New("unable to save records without an aggregate id")
+			}
+
+			bucket, err := tx.Bucket(streamsBucket).CreateBucketIfNotExists([]byte(stream.AggregateID))
+			if err != nil {
+				return fmt.Errorf("unable to create stream %q: %w", stream.AggregateID, err)
+			}
+
+			current, err := lastVersion(bucket)
+			if err != nil {
+				return err
+			}
+
+			for i, record := range stream.Records {
+				expected := current + int64(i) + 1
+				switch {
+				case record.Version < expected:
+					return fmt.Errorf("%w: version %d of %q has already been stored", es.ErrConflict, record.Version, stream.AggregateID)
+				case record.Version > expected:
+					return fmt.Errorf("%w: version %d of %q does not continue from version %d", es.ErrVersionGap, record.Version, stream.AggregateID, expected-1)
+				}
+
+				key := versionKey(record.Version)
+				if err := bucket.Put(key, recordValue(record)); err != nil {
+					return fmt.Errorf("unable to save version %d of %q: %w", record.Version, stream.AggregateID, err)
+				}
+
+				seq, err := sequence.NextSequence()
+				if err != nil {
+					return err
+				}
+				if err := sequence.Put(versionKey(int64(seq)), sequenceValue(stream.AggregateID, key)); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Load reads the records within the version range.
+func (s *Store) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int64) (es.History, error) {
+	cursor, err := s.Stream(ctx, aggregateID, fromVersion, toVersion)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	history := es.History{}
+	for cursor.Next() {
+		history = append(history, cursor.Record())
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// Stream returns a cursor over the records within the version range. Records
+// are read in batches, each within a short read transaction, so an open
+// cursor never blocks writers.
+func (s *Store) Stream(ctx context.Context, aggregateID string, fromVersion, toVersion int64) (es.Cursor, error) {
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		if tx.Bucket(streamsBucket).Bucket([]byte(aggregateID)) == nil {
+			return es.ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &cursor{
+		ctx:         ctx,
+		store:       s,
+		aggregateID: []byte(aggregateID),
+		next:        fromVersion,
+		toVersion:   toVersion,
+		index:       -1,
+	}, nil
+}
+
+// cursor implements es.Cursor by reading batches of records.
+type cursor struct {
+	ctx         context.Context
+	store       *Store
+	aggregateID []byte
+	next        int64
+	toVersion   int64
+	batch       es.History
+	index       int
+	done        bool
+	err         error
+}
+
+// Next implements the es.Cursor interface.
+func (c *cursor) Next() bool {
+	if c.err != nil {
+		return false
+	}
+
+	if err := c.ctx.Err(); err != nil {
+		c.err = err
+		return false
+	}
+
+	c.index++
+	if c.index < len(c.batch) {
+		return true
+	}
+
+	if c.done {
+		return false
+	}
+
+	c.batch, c.index = c.batch[:0], 0
+	c.err = c.store.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(streamsBucket).Bucket(c.aggregateID)
+		if bucket == nil {
+			return es.ErrNotFound
+		}
+
+		var until []byte
+		if c.toVersion != 0 {
+			until = versionKey(c.toVersion)
+		}
+
+		bc := bucket.Cursor()
+		for k, v := bc.Seek(versionKey(c.next)); k != nil; k, v = bc.Next() {
+			if until != nil && bytes.Compare(k, until) > 0 {
+				c.done = true
+				return nil
+			}
+
+			if len(c.batch) == c.store.batchSize {
+				return nil
+			}
+
+			version, err := strconv.ParseInt(string(k), 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid version key %q: %w", k, err)
+			}
+
+			record, err := parseRecord(v)
+			if err != nil {
+				return fmt.Errorf("invalid record %q of %q: %w", k, c.aggregateID, err)
+			}
+			record.Version = version
+
+			c.batch = append(c.batch, record)
+			c.next = version + 1
+		}
+
+		c.done = true
+		return nil
+	})
+
+	return c.err == nil && c.index < len(c.batch)
+}
+
+// Record implements the es.Cursor interface.
+func (c *cursor) Record() es.Record {
+	if c.index < 0 || c.index >= len(c.batch) {
+		return es.Record{}
+	}
+	return c.batch[c.index]
+}
+
+// Err implements the es.Cursor interface.
+func (c *cursor) Err() error {
+	return c.err
+}
+
+// Close implements the es.Cursor interface.
+func (c *cursor) Close() error {
+	c.batch, c.done = nil, true
+	return nil
+}
+
+// lastVersion returns the version of the last record in the bucket.
+func lastVersion(bucket *bbolt.Bucket) (int64, error) {
+	k, _ := bucket.Cursor().Last()
+	if k == nil {
+		return 0, nil
+	}
+
+	version, err := strconv.ParseInt(string(k), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version key %q: %w", k, err)
+	}
+
+	return version, nil
+}
+
+// versionKey zero pads the version so keys sort in version order.
+func versionKey(version int64) []byte {
+	return []byte(fmt.Sprintf("%020d", version))
+}
+
+// recordValue encodes the aggregate type and data of a record.
+func recordValue(record es.Record) []byte {
+	value := make([]byte, 0, binary.MaxVarintLen64+len(record.AggregateType)+len(record.Data))
+	value = binary.AppendUvarint(value, uint64(len(record.AggregateType)))
+	value = append(value, record.AggregateType...)
+	return append(value, record.Data...)
+}
+
+// parseRecord decodes a value written by recordValue, copying its data.
+func parseRecord(value []byte) (es.Record, error) {
+	n, size := binary.Uvarint(value)
+	if size <= 0 || uint64(len(value)-size) < n {
+		return es.Record{}, errors.New("malformed aggregate type")
+	}
+	value = value[size:]
+
+	return es.Record{
+		AggregateType: string(value[:n]),
+		Data:          append([]byte(nil), value[n:]...),
+	}, nil
+}
+
+// sequenceValue identifies a record from the sequence bucket.
+func sequenceValue(aggregateID string, key []byte) []byte {
+	value := make([]byte, 0, len(aggregateID)+1+len(key))
+	value = append(value, aggregateID...)
+	value = append(value, 0)
+	return append(value, key...)
+}
@@ -0,0 +1,63 @@
+package bolt_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/store/bolt"
+	"github.com/aarongreenlee/eventsource/store/storetest"
+)
+
+// open returns a store over a new file which reads small batches so cursors
+// cross batch boundaries.
+func open(t *testing.T, path string) *bolt.Store {
+	store, err := bolt.Open(path, bolt.WithBatchSize(2))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) es.Store {
+		return open(t, filepath.Join(t.TempDir(), "events.db"))
+	})
+}
+
+// TestConflict asserts versions may only be saved once and a failed save
+// leaves every stream untouched.
+func TestConflict(t *testing.T) {
+	ctx := context.Background()
+	store := open(t, filepath.Join(t.TempDir(), "events.db"))
+
+	require.NoError(t, store.Save(ctx, "a", es.Record{Version: 1, Data: []byte("1")}))
+
+	err := store.SaveAll(ctx,
+		es.StreamRecords{AggregateID: "b", Records: es.History{{Version: 1, Data: []byte("1")}}},
+		es.StreamRecords{AggregateID: "a", Records: es.History{{Version: 1, Data: []byte("again")}}},
+	)
+	assert.True(t, errors.Is(err, es.ErrConflict), "expected a conflict but found %v", err)
+
+	_, err = store.Load(ctx, "b", 0, 0)
+	assert.True(t, errors.Is(err, es.ErrNotFound))
+}
+
+// TestDurable asserts records survive the store being reopened.
+func TestDurable(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.db")
+
+	store, err := bolt.Open(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Save(ctx, "a", es.Record{Version: 1, Data: []byte("1")}, es.Record{Version: 2, Data: []byte("2")}))
+	require.NoError(t, store.Close())
+
+	history, err := open(t, path).Load(ctx, "a", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, es.History{{Version: 1, Data: []byte("1")}, {Version: 2, Data: []byte("2")}}, history)
+}
@@ -7,64 +7,259 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	es "github.com/aarongreenlee/eventsource"
 )
 
+// shardCount is the number of shards aggregates are spread across so writers
+// to different aggregates rarely contend for the same lock.
+const shardCount = 64
+
 // memoryStore provides an in-memory implementation of Store
 type memoryStore struct {
-	*sync.Mutex
-	eventsByID map[string]es.History
+	shards [shardCount]shard
+	now    func() time.Time
+}
+
+// shard guards the streams of the aggregates hashed to it.
+type shard struct {
+	sync.RWMutex
+	streams map[string]*stream
+}
+
+// stream is the history of a single aggregate. The history is only ever
+// appended to so slices handed to readers are never modified.
+type stream struct {
+	history es.History
+	info    es.AggregateInfo
+}
+
+// Option provides functional configuration for a *memoryStore
+type Option func(*memoryStore)
+
+// WithClock replaces the clock used to record when an aggregate was created.
+func WithClock(now func() time.Time) Option {
+	return func(m *memoryStore) {
+		m.now = now
+	}
 }
 
 // New produces a new memory store that meets the eventsource.Store interface.
-func New() *memoryStore {
-	return &memoryStore{
-		Mutex:      &sync.Mutex{},
-		eventsByID: map[string]es.History{},
+func New(opts ...Option) *memoryStore {
+	m := &memoryStore{now: time.Now}
+	for i := range m.shards {
+		m.shards[i].streams = map[string]*stream{}
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// shard returns the shard holding the aggregate, chosen by the 32-bit FNV-1a
+// hash of the aggregate ID.
+func (m *memoryStore) shard(aggregateID string) *shard {
+	h := uint32(2166136261)
+	for i := 0; i < len(aggregateID); i++ {
+		h ^= uint32(aggregateID[i])
+		h *= 16777619
 	}
+	return &m.shards[h%shardCount]
 }
 
-// Save stores the record(s) in memory which then become history.
+// Save appends the record(s) to the aggregate's history. The records must
+// continue from the last version stored; es.ErrConflict is returned if any
+// version has already been stored.
 func (m *memoryStore) Save(ctx context.Context, aggregateID string, records ...es.Record) error {
-	m.Lock()
-	defer m.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s := m.shard(aggregateID)
+	s.Lock()
+	defer s.Unlock()
 
-	if _, ok := m.eventsByID[aggregateID]; !ok {
-		m.eventsByID[aggregateID] = es.History{}
+	if err := check(aggregateID, s.version(aggregateID), records); err != nil {
+		return err
 	}
 
-	history := append(m.eventsByID[aggregateID], records...)
-	sort.Sort(history)
-	m.eventsByID[aggregateID] = history
+	if len(records) > 0 {
+		s.append(aggregateID, records, m.now())
+	}
 
 	return nil
 }
 
-// Load returns the history from memory if any.
-func (m *memoryStore) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int64) (es.History, error) {
-	m.Lock()
-	defer m.Unlock()
+// SaveAll appends the records of every stream or, if any stream fails, none
+// of them. The shards of every stream are locked while saving so readers
+// observe either all or none of the streams.
+func (m *memoryStore) SaveAll(ctx context.Context, streams ...es.StreamRecords) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Shards are locked in index order so concurrent calls can not deadlock.
+	locked := map[*shard]bool{}
+	for _, s := range streams {
+		locked[m.shard(s.AggregateID)] = true
+	}
+
+	shards := make([]*shard, 0, len(locked))
+	for i := range m.shards {
+		if locked[&m.shards[i]] {
+			shards = append(shards, &m.shards[i])
+		}
+	}
+
+	for _, s := range shards {
+		s.Lock()
+		defer s.Unlock()
+	}
+
+	// Every stream is checked before any is appended to so a failure leaves
+	// the store untouched. Versions continue from earlier streams of the same
+	// aggregate within this call.
+	pending := map[string]int64{}
+	for _, s := range streams {
+		current, ok := pending[s.AggregateID]
+		if !ok {
+			current = m.shard(s.AggregateID).version(s.AggregateID)
+		}
 
-	all, ok := m.eventsByID[aggregateID]
+		if err := check(s.AggregateID, current, s.Records); err != nil {
+			return err
+		}
+
+		pending[s.AggregateID] = current + int64(len(s.Records))
+	}
+
+	now := m.now()
+	for _, s := range streams {
+		if len(s.Records) > 0 {
+			m.shard(s.AggregateID).append(s.AggregateID, s.Records, now)
+		}
+	}
+
+	return nil
+}
+
+// check returns an error unless the records continue, without gaps, from the
+// current version.
+func check(aggregateID string, current int64, records es.History) error {
+	for i, record := range records {
+		expected := current + int64(i) + 1
+		switch {
+		case record.Version < expected:
+			return fmt.Errorf("%w: version %d of %q has already been stored", es.ErrConflict, record.Version, aggregateID)
+		case record.Version > expected:
+			return fmt.Errorf("%w: version %d of %q does not continue from version %d", es.ErrVersionGap, record.Version, aggregateID, expected-1)
+		}
+	}
+	return nil
+}
+
+// version returns the last version stored for the aggregate. The caller must
+// hold the lock.
+func (s *shard) version(aggregateID string) int64 {
+	if st, ok := s.streams[aggregateID]; ok {
+		return st.info.Version
+	}
+	return 0
+}
+
+// append adds the records, which have been checked, to the aggregate's
+// history. The caller must hold the lock.
+func (s *shard) append(aggregateID string, records es.History, now time.Time) {
+	st, ok := s.streams[aggregateID]
 	if !ok {
+		st = &stream{info: es.AggregateInfo{AggregateID: aggregateID, CreatedAt: now}}
+		s.streams[aggregateID] = st
+	}
 
-		for id := range m.eventsByID {
-			fmt.Printf("asked for %q, found %q\n", aggregateID, id)
+	st.history = append(st.history, records...)
+	st.info.Version = records[len(records)-1].Version
+	for _, record := range records {
+		if st.info.AggregateType == "" {
+			st.info.AggregateType = record.AggregateType
 		}
+	}
+}
+
+// snapshot returns the records of the aggregate within the version range. The
+// records are shared with the store.
+func (m *memoryStore) snapshot(aggregateID string, fromVersion, toVersion int64) (es.History, bool) {
+	s := m.shard(aggregateID)
+	s.RLock()
+	defer s.RUnlock()
+
+	st, ok := s.streams[aggregateID]
+	if !ok {
+		return nil, false
+	}
+
+	return st.history.Between(fromVersion, toVersion), true
+}
+
+// Load returns the history from memory if any.
+func (m *memoryStore) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int64) (es.History, error) {
+	between, ok := m.snapshot(aggregateID, fromVersion, toVersion)
+	if !ok {
+		return nil, es.ErrNotFound
+	}
 
+	history := make(es.History, len(between))
+	copy(history, between)
+
+	return history, nil
+}
+
+// Stream returns a cursor over the history held in memory. The records are
+// not copied; the cursor reads a snapshot of the history taken when Stream is
+// called.
+func (m *memoryStore) Stream(ctx context.Context, aggregateID string, fromVersion, toVersion int64) (es.Cursor, error) {
+	between, ok := m.snapshot(aggregateID, fromVersion, toVersion)
+	if !ok {
 		return nil, es.ErrNotFound
 	}
 
-	history := make(es.History, 0, len(all))
+	return es.NewHistoryCursor(ctx, between), nil
+}
+
+// List pages through the aggregates held in memory ordered by aggregate ID.
+// The page token is the last aggregate ID of the previous page.
+func (m *memoryStore) List(ctx context.Context, query es.ListQuery) (es.ListPage, error) {
+	if err := ctx.Err(); err != nil {
+		return es.ListPage{}, err
+	}
 
-	if len(all) > 0 {
-		for _, record := range all {
-			if v := record.Version; v >= fromVersion && (toVersion == 0 || v <= toVersion) {
-				history = append(history, record)
+	var matches []es.AggregateInfo
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.RLock()
+		for id, st := range s.streams {
+			if id > query.Token && query.Matches(st.info) {
+				matches = append(matches, st.info)
 			}
 		}
+		s.RUnlock()
 	}
 
-	return history, nil
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].AggregateID < matches[j].AggregateID
+	})
+
+	page := es.ListPage{Aggregates: []es.AggregateInfo{}}
+	for _, info := range matches {
+		if query.Limit > 0 && len(page.Aggregates) == query.Limit {
+			page.Next = page.Aggregates[len(page.Aggregates)-1].AggregateID
+			break
+		}
+
+		page.Aggregates = append(page.Aggregates, info)
+	}
+
+	return page, nil
 }
@@ -0,0 +1,89 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/store/memory"
+	"github.com/aarongreenlee/eventsource/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) es.Store {
+		return memory.New()
+	})
+}
+
+func TestListCreated(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := memory.New(memory.WithClock(func() time.Time { return now }))
+
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, store.Save(ctx, id, es.Record{Version: 1}))
+		now = now.Add(time.Hour)
+	}
+	require.NoError(t, store.Save(ctx, "a", es.Record{Version: 2}))
+
+	page, err := store.List(ctx, es.ListQuery{
+		CreatedAfter:  time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		CreatedBefore: time.Date(2020, 1, 1, 3, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []es.AggregateInfo{
+		{AggregateID: "b", CreatedAt: time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC), Version: 1},
+		{AggregateID: "c", CreatedAt: time.Date(2020, 1, 1, 2, 0, 0, 0, time.UTC), Version: 1},
+	}, page.Aggregates)
+}
+
+// BenchmarkSaveParallel measures parallel writers appending to many
+// aggregates.
+func BenchmarkSaveParallel(b *testing.B) {
+	ctx := context.Background()
+	store := memory.New()
+	var writer int64
+
+	b.RunParallel(func(pb *testing.PB) {
+		id := fmt.Sprintf("aggregate-%d", atomic.AddInt64(&writer, 1))
+		var version int64
+		for pb.Next() {
+			version++
+			if err := store.Save(ctx, id, es.Record{Version: version, Data: []byte("data")}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkLoadParallel measures parallel readers of aggregates which are
+// being appended to.
+func BenchmarkLoadParallel(b *testing.B) {
+	ctx := context.Background()
+	store := memory.New()
+
+	const aggregates = 1024
+	for i := 0; i < aggregates; i++ {
+		for v := int64(1); v <= 10; v++ {
+			if err := store.Save(ctx, fmt.Sprintf("aggregate-%d", i), es.Record{Version: v}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	var reader int64
+	b.RunParallel(func(pb *testing.PB) {
+		n := atomic.AddInt64(&reader, 1)
+		for i := n; pb.Next(); i++ {
+			if _, err := store.Load(ctx, fmt.Sprintf("aggregate-%d", i%aggregates), 0, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Dialect adapts the store to a database. Implement Dialect to support a
+// database other than those provided.
+type Dialect interface {
+	// Placeholder returns the bind parameter for the nth argument of a
+	// statement, starting at 1.
+	Placeholder(n int) string
+
+	// Migration returns the statements which create the events table and
+	// its indexes if they do not exist. The table must hold a global
+	// sequence, seq, the aggregate_id, aggregate_type, version and data of
+	// each record, and enforce a unique (aggregate_id, version) constraint.
+	Migration(table string) []string
+
+	// IsUniqueViolation reports whether the error was caused by a unique
+	// constraint.
+	IsUniqueViolation(err error) bool
+}
+
+// SQLite supports SQLite databases.
+var SQLite Dialect = sqlite{}
+
+// Postgres supports PostgreSQL databases.
+var Postgres Dialect = postgres{}
+
+// sqlite implements Dialect for SQLite.
+type sqlite struct{}
+
+func (sqlite) Placeholder(int) string {
+	return "?"
+}
+
+func (sqlite) Migration(table string) []string {
+	return []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	aggregate_id TEXT NOT NULL,
+	aggregate_type TEXT NOT NULL DEFAULT '',
+	version INTEGER NOT NULL,
+	data BLOB NOT NULL,
+	UNIQUE (aggregate_id, version)
+)`, table),
+	}
+}
+
+func (sqlite) IsUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// postgres implements Dialect for PostgreSQL.
+type postgres struct{}
+
+func (postgres) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (postgres) Migration(table string) []string {
+	return []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	seq BIGSERIAL PRIMARY KEY,
+	aggregate_id TEXT NOT NULL,
+	aggregate_type TEXT NOT NULL DEFAULT '',
+	version BIGINT NOT NULL,
+	data BYTEA NOT NULL,
+	UNIQUE (aggregate_id, version)
+)`, table),
+	}
+}
+
+// sqlState is implemented by the errors of common PostgreSQL drivers.
+type sqlState interface {
+	SQLState() string
+}
+
+func (postgres) IsUniqueViolation(err error) bool {
+	var s sqlState
+	return errors.As(err, &s) && s.SQLState() == "23505"
+}
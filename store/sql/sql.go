@@ -0,0 +1,292 @@
+// Package sql implements an eventsource.Store over database/sql. Records are
+// kept in a single events table holding a global sequence and a unique
+// (aggregate_id, version) constraint which provides optimistic concurrency:
+// saving a version which has already been stored fails with
+// eventsource.ErrConflict.
+package sql
+
+import (
+	"context"
+	stdsql "database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	es "github.com/aarongreenlee/eventsource"
+)
+
+// DefaultTable names the events table unless WithTable is provided.
+const DefaultTable = "events"
+
+// Store persists records to a relational database.
+type Store struct {
+	db      *stdsql.DB
+	dialect Dialect
+	table   string
+}
+
+// Option provides functional configuration for a *Store
+type Option func(*Store) error
+
+// tableName restricts table names to identifiers which need no quoting.
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// WithTable names the events table.
+func WithTable(table string) Option {
+	return func(s *Store) error {
+		if !tableName.MatchString(table) {
+			return fmt.Errorf("invalid table name %q", table)
+		}
+		s.table = table
+		return nil
+	}
+}
+
+// New produces a new store over the database using the dialect provided.
+// Call Migrate to create the events table.
+func New(db *stdsql.DB, dialect Dialect, opts ...Option) (*Store, error) {
+	if db == nil {
+		return nil, errors.New("must not provide a nil database")
+	}
+	if dialect == nil {
+		return nil, errors.New("must not provide a nil dialect")
+	}
+
+	s := &Store{
+		db:      db,
+		dialect: dialect,
+		table:   DefaultTable,
+	}
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, fmt.Errorf("error applying option: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// Migrate creates the events table if it does not exist.
+func (s *Store) Migrate(ctx context.Context) error {
+	for _, statement := range s.dialect.Migration(s.table) {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("unable to migrate %s: %w", s.table, err)
+		}
+	}
+	return nil
+}
+
+// Save persists the records within a transaction. The records must continue
+// from the last version stored; es.ErrConflict is returned if any version has
+// already been stored.
+func (s *Store) Save(ctx context.Context, aggregateID string, records ...es.Record) error {
+	return s.SaveAll(ctx, es.StreamRecords{AggregateID: aggregateID, Records: records})
+}
+
+// SaveAll persists the records of every stream within a single transaction.
+func (s *Store) SaveAll(ctx context.Context, streams ...es.StreamRecords) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", err)
+	}
+
+	for _, stream := range streams {
+		if err := s.save(ctx, tx, stream.AggregateID, stream.Records); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		if s.dialect.IsUniqueViolation(err) {
+			return fmt.Errorf("%w: %s", es.ErrConflict, err)
+		}
+		return fmt.Errorf("unable to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// save inserts the records of a single aggregate within the transaction.
+func (s *Store) save(ctx context.Context, tx *stdsql.Tx, aggregateID string, records es.History) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	var current int64
+	err := tx.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s WHERE aggregate_id = %s", s.table, s.dialect.Placeholder(1)),
+		aggregateID,
+	).Scan(&current)
+	if err != nil {
+		return fmt.Errorf("unable to read version of %q: %w", aggregateID, err)
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s (aggregate_id, aggregate_type, version, data) VALUES (%s, %s, %s, %s)",
+		s.table, s.dialect.Placeholder(1), s.dialect.Placeholder(2), s.dialect.Placeholder(3), s.dialect.Placeholder(4))
+
+	for i, record := range records {
+		expected := current + int64(i) + 1
+		switch {
+		case record.Version < expected:
+			return fmt.Errorf("%w: version %d of %q has already been stored", es.ErrConflict, record.Version, aggregateID)
+		case record.Version > expected:
+			return fmt.Errorf("%w: version %d of %q does not continue from version %d", es.ErrVersionGap, record.Version, aggregateID, expected-1)
+		}
+
+		// The data column is NOT NULL, so nil data is stored as empty.
+		data := record.Data
+		if data == nil {
+			data = []byte{}
+		}
+
+		if _, err := tx.ExecContext(ctx, insert, aggregateID, record.AggregateType, record.Version, data); err != nil {
+			if s.dialect.IsUniqueViolation(err) {
+				return fmt.Errorf("%w: version %d of %q has already been stored", es.ErrConflict, record.Version, aggregateID)
+			}
+			return fmt.Errorf("unable to save version %d of %q: %w", record.Version, aggregateID, err)
+		}
+	}
+
+	return nil
+}
+
+// Load reads the records within the version range.
+func (s *Store) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int64) (es.History, error) {
+	cursor, err := s.Stream(ctx, aggregateID, fromVersion, toVersion)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	history := es.History{}
+	for cursor.Next() {
+		history = append(history, cursor.Record())
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// Stream returns a cursor over the records within the version range. The
+// cursor holds a database connection until it is closed.
+func (s *Store) Stream(ctx context.Context, aggregateID string, fromVersion, toVersion int64) (es.Cursor, error) {
+	var query strings.Builder
+	fmt.Fprintf(&query, "SELECT aggregate_type, version, data FROM %s WHERE aggregate_id = %s AND version >= %s",
+		s.table, s.dialect.Placeholder(1), s.dialect.Placeholder(2))
+
+	args := []interface{}{aggregateID, fromVersion}
+	if toVersion != 0 {
+		fmt.Fprintf(&query, " AND version <= %s", s.dialect.Placeholder(3))
+		args = append(args, toVersion)
+	}
+	query.WriteString(" ORDER BY version")
+
+	rows, err := s.db.QueryContext(ctx, query.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load %q: %w", aggregateID, err)
+	}
+
+	return &cursor{
+		ctx:         ctx,
+		store:       s,
+		rows:        rows,
+		aggregateID: aggregateID,
+	}, nil
+}
+
+// exists reports whether any record has been stored for the aggregate.
+func (s *Store) exists(ctx context.Context, aggregateID string) (bool, error) {
+	var found int
+	err := s.db.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT 1 FROM %s WHERE aggregate_id = %s LIMIT 1", s.table, s.dialect.Placeholder(1)),
+		aggregateID,
+	).Scan(&found)
+
+	switch {
+	case errors.Is(err, stdsql.ErrNoRows):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+
+	return true, nil
+}
+
+// cursor implements es.Cursor over query results.
+type cursor struct {
+	ctx         context.Context
+	store       *Store
+	rows        *stdsql.Rows
+	aggregateID string
+	record      es.Record
+	count       int
+	err         error
+}
+
+// Next implements the es.Cursor interface. When the query returns no rows the
+// cursor reports es.ErrNotFound if the aggregate has never been saved.
+func (c *cursor) Next() bool {
+	if c.err != nil || c.rows == nil {
+		return false
+	}
+
+	if c.rows.Next() {
+		c.record = es.Record{}
+		if err := c.rows.Scan(&c.record.AggregateType, &c.record.Version, &c.record.Data); err != nil {
+			c.err = fmt.Errorf("unable to read record of %q: %w", c.aggregateID, err)
+			return false
+		}
+		c.count++
+		return true
+	}
+
+	if err := c.rows.Err(); err != nil {
+		c.err = err
+		return false
+	}
+
+	if err := c.ctx.Err(); err != nil {
+		c.err = err
+		return false
+	}
+
+	if c.count == 0 {
+		_ = c.rows.Close()
+		ok, err := c.store.exists(c.ctx, c.aggregateID)
+		switch {
+		case err != nil:
+			c.err = err
+		case !ok:
+			c.err = es.ErrNotFound
+		}
+	}
+
+	_ = c.Close()
+	return false
+}
+
+// Record implements the es.Cursor interface.
+func (c *cursor) Record() es.Record {
+	return c.record
+}
+
+// Err implements the es.Cursor interface.
+func (c *cursor) Err() error {
+	return c.err
+}
+
+// Close implements the es.Cursor interface.
+func (c *cursor) Close() error {
+	if c.rows == nil {
+		return nil
+	}
+	rows := c.rows
+	c.rows = nil
+	return rows.Close()
+}
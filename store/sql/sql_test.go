@@ -0,0 +1,114 @@
+package sql_test
+
+import (
+	"context"
+	stdsql "database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/store/sql"
+	"github.com/aarongreenlee/eventsource/store/storetest"
+)
+
+// open returns a migrated store over a new SQLite database. Transactions
+// take the write lock when they begin so concurrent writers queue rather
+// than fail with a busy database.
+func open(t *testing.T) *sql.Store {
+	dsn := filepath.Join(t.TempDir(), "events.db") + "?_pragma=busy_timeout(5000)&_txlock=immediate"
+
+	db, err := stdsql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	store, err := sql.New(db, sql.SQLite)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.Background()))
+
+	return store
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) es.Store {
+		return open(t)
+	})
+}
+
+// TestConflict asserts versions may only be saved once.
+func TestConflict(t *testing.T) {
+	ctx := context.Background()
+	store := open(t)
+
+	require.NoError(t, store.Save(ctx, "a", es.Record{Version: 1, Data: []byte("1")}))
+
+	err := store.Save(ctx, "a", es.Record{Version: 1, Data: []byte("again")})
+	assert.True(t, errors.Is(err, es.ErrConflict), "expected a conflict but found %v", err)
+
+	// A failed transaction saves none of its streams.
+	err = store.SaveAll(ctx,
+		es.StreamRecords{AggregateID: "b", Records: es.History{{Version: 1, Data: []byte("1")}}},
+		es.StreamRecords{AggregateID: "a", Records: es.History{{Version: 1, Data: []byte("again")}}},
+	)
+	assert.True(t, errors.Is(err, es.ErrConflict))
+
+	_, err = store.Load(ctx, "b", 0, 0)
+	assert.True(t, errors.Is(err, es.ErrNotFound))
+}
+
+// TestConcurrentWriters asserts only one of several writers racing to save
+// the same version succeeds.
+func TestConcurrentWriters(t *testing.T) {
+	ctx := context.Background()
+	store := open(t)
+
+	const writers = 8
+	results := make(chan error, writers)
+
+	for i := 0; i < writers; i++ {
+		go func(i int) {
+			results <- store.Save(ctx, "a", es.Record{Version: 1, Data: []byte(fmt.Sprint(i))})
+		}(i)
+	}
+
+	var saved int
+	for i := 0; i < writers; i++ {
+		err := <-results
+		if err == nil {
+			saved++
+			continue
+		}
+		assert.True(t, errors.Is(err, es.ErrConflict), "expected a conflict but found %v", err)
+	}
+
+	assert.Equal(t, 1, saved)
+}
+
+// TestWithTable asserts table names are validated.
+func TestWithTable(t *testing.T) {
+	_, err := sql.New(&stdsql.DB{}, sql.SQLite, sql.WithTable("events; DROP TABLE events"))
+	assert.Error(t, err)
+}
+
+// pgError mimics the errors of PostgreSQL drivers.
+type pgError struct {
+	code string
+}
+
+func (e *pgError) Error() string    { return "duplicate key value violates unique constraint" }
+func (e *pgError) SQLState() string { return e.code }
+
+// TestPostgresUniqueViolation asserts unique violations are recognised by
+// their SQLSTATE, even when wrapped, rather than by their message.
+func TestPostgresUniqueViolation(t *testing.T) {
+	assert.True(t, sql.Postgres.IsUniqueViolation(&pgError{code: "23505"}))
+	assert.True(t, sql.Postgres.IsUniqueViolation(fmt.Errorf("insert: %w", &pgError{code: "23505"})))
+	assert.False(t, sql.Postgres.IsUniqueViolation(&pgError{code: "23503"}))
+	assert.False(t, sql.Postgres.IsUniqueViolation(errors.New("duplicate key value violates unique constraint")))
+	assert.False(t, sql.Postgres.IsUniqueViolation(nil))
+}
@@ -0,0 +1,354 @@
+// Package storetest provides a conformance suite which every
+// eventsource.Store implementation is expected to pass.
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) es.Store {
+//			return memory.New()
+//		})
+//	}
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	es "github.com/aarongreenlee/eventsource"
+)
+
+// Run exercises a Store. The factory must return an empty store on every
+// call. Optional interfaces, such as es.Streamer and es.TxStore, are exercised
+// when the store implements them.
+func Run(t *testing.T, factory func(t *testing.T) es.Store) {
+	t.Helper()
+
+	t.Run("SaveLoad", func(t *testing.T) {
+		testSaveLoad(t, factory(t))
+	})
+	t.Run("EmptyData", func(t *testing.T) {
+		testEmptyData(t, factory(t))
+	})
+	t.Run("Range", func(t *testing.T) {
+		testRange(t, factory(t))
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		testNotFound(t, factory(t))
+	})
+	t.Run("Stream", func(t *testing.T) {
+		testStream(t, factory(t))
+	})
+	t.Run("Conflict", func(t *testing.T) {
+		testConflict(t, factory(t))
+	})
+	t.Run("SaveAll", func(t *testing.T) {
+		testSaveAll(t, factory(t))
+	})
+	t.Run("List", func(t *testing.T) {
+		testList(t, factory(t))
+	})
+}
+
+// aggregateType is the aggregate type of the records built by records.
+const aggregateType = "Sample"
+
+// records builds records for the versions from and to inclusive.
+func records(aggregateID string, from, to int64) es.History {
+	history := make(es.History, 0, to-from+1)
+	for v := from; v <= to; v++ {
+		history = append(history, es.Record{
+			Version:       v,
+			Data:          []byte(fmt.Sprintf("%s-%d", aggregateID, v)),
+			AggregateType: aggregateType,
+		})
+	}
+	return history
+}
+
+// expect fails the test unless the history holds the versions from and to
+// inclusive with the data and aggregate type produced by records.
+func expect(t *testing.T, history es.History, aggregateID string, from, to int64) {
+	t.Helper()
+
+	want := records(aggregateID, from, to)
+	if len(history) != len(want) {
+		t.Fatalf("expected %d records but found %d", len(want), len(history))
+	}
+
+	for i := range want {
+		if history[i].Version != want[i].Version {
+			t.Fatalf("expected version %d at %d but found %d", want[i].Version, i, history[i].Version)
+		}
+		if string(history[i].Data) != string(want[i].Data) {
+			t.Fatalf("expected data %q at %d but found %q", want[i].Data, i, history[i].Data)
+		}
+		if history[i].AggregateType != want[i].AggregateType {
+			t.Fatalf("expected aggregate type %q at %d but found %q", want[i].AggregateType, i, history[i].AggregateType)
+		}
+	}
+}
+
+// testSaveLoad asserts records saved across several calls are loaded in
+// version order and streams do not mix.
+func testSaveLoad(t *testing.T, store es.Store) {
+	ctx := context.Background()
+
+	must(t, store.Save(ctx, "a", records("a", 1, 2)...))
+	must(t, store.Save(ctx, "b", records("b", 1, 1)...))
+	must(t, store.Save(ctx, "a", records("a", 3, 5)...))
+
+	history, err := store.Load(ctx, "a", 0, 0)
+	must(t, err)
+	expect(t, history, "a", 1, 5)
+
+	history, err = store.Load(ctx, "b", 0, 0)
+	must(t, err)
+	expect(t, history, "b", 1, 1)
+}
+
+// testEmptyData asserts records with nil or empty data may be saved and are
+// loaded with empty data.
+func testEmptyData(t *testing.T, store es.Store) {
+	ctx := context.Background()
+
+	must(t, store.Save(ctx, "a",
+		es.Record{Version: 1},
+		es.Record{Version: 2, Data: []byte{}},
+	))
+
+	history, err := store.Load(ctx, "a", 0, 0)
+	must(t, err)
+
+	if len(history) != 2 {
+		t.Fatalf("expected 2 records but found %d", len(history))
+	}
+	for i, record := range history {
+		if len(record.Data) != 0 {
+			t.Fatalf("expected empty data at %d but found %q", i, record.Data)
+		}
+	}
+}
+
+// testRange asserts the fromVersion and toVersion bounds are inclusive and
+// a toVersion of 0 reads to the end of the history.
+func testRange(t *testing.T, store es.Store) {
+	ctx := context.Background()
+
+	must(t, store.Save(ctx, "a", records("a", 1, 5)...))
+
+	history, err := store.Load(ctx, "a", 2, 4)
+	must(t, err)
+	expect(t, history, "a", 2, 4)
+
+	history, err = store.Load(ctx, "a", 3, 0)
+	must(t, err)
+	expect(t, history, "a", 3, 5)
+
+	history, err = store.Load(ctx, "a", 0, 2)
+	must(t, err)
+	expect(t, history, "a", 1, 2)
+
+	history, err = store.Load(ctx, "a", 6, 0)
+	must(t, err)
+	if len(history) != 0 {
+		t.Fatalf("expected no records beyond the last version but found %d", len(history))
+	}
+}
+
+// testNotFound asserts loading an aggregate which has never been saved fails
+// with es.ErrNotFound.
+func testNotFound(t *testing.T, store es.Store) {
+	_, err := store.Load(context.Background(), "missing", 0, 0)
+	if !errors.Is(err, es.ErrNotFound) {
+		t.Fatalf("expected %q but found %v", es.ErrNotFound, err)
+	}
+}
+
+// testStream asserts a Cursor yields the same records as Load, may be closed
+// early and reports es.ErrNotFound for unknown aggregates.
+func testStream(t *testing.T, store es.Store) {
+	ctx := context.Background()
+
+	must(t, store.Save(ctx, "a", records("a", 1, 5)...))
+
+	history := read(t, ctx, store, "a", 2, 4)
+	expect(t, history, "a", 2, 4)
+
+	cursor, err := es.Stream(ctx, store, "a", 0, 0)
+	must(t, err)
+	if !cursor.Next() {
+		t.Fatalf("expected a record: %v", cursor.Err())
+	}
+	must(t, cursor.Close())
+
+	cursor, err = es.Stream(ctx, store, "missing", 0, 0)
+	if err == nil {
+		for cursor.Next() {
+			t.Fatalf("expected no records for an unknown aggregate")
+		}
+		err = cursor.Err()
+		cursor.Close()
+	}
+	if !errors.Is(err, es.ErrNotFound) {
+		t.Fatalf("expected %q but found %v", es.ErrNotFound, err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cursor, err = es.Stream(canceled, store, "a", 0, 0)
+	must(t, err)
+	defer cursor.Close()
+
+	cancel()
+	for cursor.Next() {
+	}
+	if !errors.Is(cursor.Err(), context.Canceled) {
+		t.Fatalf("expected %q but found %v", context.Canceled, cursor.Err())
+	}
+}
+
+// read collects the records of a Cursor.
+func read(t *testing.T, ctx context.Context, store es.Store, aggregateID string, from, to int64) es.History {
+	t.Helper()
+
+	cursor, err := es.Stream(ctx, store, aggregateID, from, to)
+	must(t, err)
+	defer cursor.Close()
+
+	var history es.History
+	for cursor.Next() {
+		history = append(history, cursor.Record())
+	}
+	must(t, cursor.Err())
+
+	return history
+}
+
+// testConflict asserts versions which have already been stored are rejected
+// with es.ErrConflict, gaps with es.ErrVersionGap and failed saves change nothing.
+func testConflict(t *testing.T, store es.Store) {
+	ctx := context.Background()
+
+	must(t, store.Save(ctx, "a", records("a", 1, 2)...))
+
+	err := store.Save(ctx, "a", records("a", 2, 3)...)
+	if !errors.Is(err, es.ErrConflict) {
+		t.Fatalf("expected %q but found %v", es.ErrConflict, err)
+	}
+
+	err = store.Save(ctx, "a", records("a", 4, 4)...)
+	if !errors.Is(err, es.ErrVersionGap) {
+		t.Fatalf("expected %q saving version 4 after version 2 but found %v", es.ErrVersionGap, err)
+	}
+
+	history, err := store.Load(ctx, "a", 0, 0)
+	must(t, err)
+	expect(t, history, "a", 1, 2)
+
+	tx, ok := store.(es.TxStore)
+	if !ok {
+		return
+	}
+
+	err = tx.SaveAll(ctx,
+		es.StreamRecords{AggregateID: "b", Records: records("b", 1, 1)},
+		es.StreamRecords{AggregateID: "a", Records: records("a", 1, 1)},
+	)
+	if !errors.Is(err, es.ErrConflict) {
+		t.Fatalf("expected %q but found %v", es.ErrConflict, err)
+	}
+
+	if _, err := store.Load(ctx, "b", 0, 0); !errors.Is(err, es.ErrNotFound) {
+		t.Fatalf("expected no records to be saved by a failed SaveAll but found %v", err)
+	}
+}
+
+// testSaveAll asserts stores implementing es.TxStore save several streams.
+func testSaveAll(t *testing.T, store es.Store) {
+	tx, ok := store.(es.TxStore)
+	if !ok {
+		t.Skipf("%T does not implement TxStore", store)
+	}
+
+	ctx := context.Background()
+
+	must(t, tx.SaveAll(ctx,
+		es.StreamRecords{AggregateID: "a", Records: records("a", 1, 2)},
+		es.StreamRecords{AggregateID: "b", Records: records("b", 1, 3)},
+	))
+
+	history, err := store.Load(ctx, "a", 0, 0)
+	must(t, err)
+	expect(t, history, "a", 1, 2)
+
+	history, err = store.Load(ctx, "b", 0, 0)
+	must(t, err)
+	expect(t, history, "b", 1, 3)
+}
+
+// testList asserts stores implementing es.Lister filter by aggregate type and
+// last version and page through every match exactly once.
+func testList(t *testing.T, store es.Store) {
+	lister, ok := store.(es.Lister)
+	if !ok {
+		t.Skipf("%T does not implement Lister", store)
+	}
+
+	ctx := context.Background()
+
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		history := records(id, 1, int64(i+1))
+		for j := range history {
+			history[j].AggregateType = "even"
+			if i%2 == 0 {
+				history[j].AggregateType = "odd"
+			}
+		}
+		must(t, store.Save(ctx, id, history...))
+	}
+
+	ids := func(query es.ListQuery) []string {
+		t.Helper()
+
+		var found []string
+		for {
+			page, err := lister.List(ctx, query)
+			must(t, err)
+			if query.Limit > 0 && len(page.Aggregates) > query.Limit {
+				t.Fatalf("expected at most %d aggregates but found %d", query.Limit, len(page.Aggregates))
+			}
+			for _, info := range page.Aggregates {
+				found = append(found, info.AggregateID)
+			}
+			if page.Next == "" {
+				return found
+			}
+			query.Token = page.Next
+		}
+	}
+
+	cases := map[string]struct {
+		Query    es.ListQuery
+		Expected []string
+	}{
+		"all":     {Query: es.ListQuery{}, Expected: []string{"a", "b", "c", "d", "e"}},
+		"paged":   {Query: es.ListQuery{Limit: 2}, Expected: []string{"a", "b", "c", "d", "e"}},
+		"type":    {Query: es.ListQuery{AggregateType: "odd", Limit: 1}, Expected: []string{"a", "c", "e"}},
+		"version": {Query: es.ListQuery{MinVersion: 2, MaxVersion: 4}, Expected: []string{"b", "c", "d"}},
+		"created": {Query: es.ListQuery{CreatedBefore: time.Now().Add(-time.Hour)}},
+	}
+
+	for label, tc := range cases {
+		found := ids(tc.Query)
+		if fmt.Sprint(found) != fmt.Sprint(tc.Expected) {
+			t.Fatalf("%s: expected %v but found %v", label, tc.Expected, found)
+		}
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
@@ -1,6 +1,7 @@
 package eventsource_test
 
 import (
+	"context"
 	"sort"
 	"testing"
 
@@ -26,3 +27,96 @@ func TestHistorySort(t *testing.T) {
 		}
 	}
 }
+
+// TestHistoryBetween asserts a sorted History can be narrowed to a version
+// range using the same rules as Store.Load.
+func TestHistoryBetween(t *testing.T) {
+	history := eventsource.History{
+		{Version: 1},
+		{Version: 2},
+		{Version: 3},
+		{Version: 4},
+	}
+
+	testCases := []struct {
+		from, to int64
+		expect   []int64
+	}{
+		{from: 0, to: 0, expect: []int64{1, 2, 3, 4}},
+		{from: 2, to: 0, expect: []int64{2, 3, 4}},
+		{from: 0, to: 3, expect: []int64{1, 2, 3}},
+		{from: 2, to: 3, expect: []int64{2, 3}},
+		{from: 5, to: 0, expect: []int64{}},
+		{from: 3, to: 2, expect: []int64{}},
+	}
+
+	for _, tc := range testCases {
+		found := history.Between(tc.from, tc.to)
+		if len(found) != len(tc.expect) {
+			t.Fatalf("from %d to %d: expected %d records but found %d", tc.from, tc.to, len(tc.expect), len(found))
+		}
+		for i, v := range tc.expect {
+			if found[i].Version != v {
+				t.Fatalf("from %d to %d: expected version %d but found %d", tc.from, tc.to, v, found[i].Version)
+			}
+		}
+	}
+}
+
+// loadOnlyStore implements only the slice returning Store methods.
+type loadOnlyStore struct {
+	history eventsource.History
+}
+
+func (s loadOnlyStore) Save(context.Context, string, ...eventsource.Record) error {
+	return nil
+}
+
+func (s loadOnlyStore) Load(context.Context, string, int64, int64) (eventsource.History, error) {
+	return s.history, nil
+}
+
+// TestStreamAdaptsLoad asserts stores which do not implement Streamer can
+// still be read through a Cursor.
+func TestStreamAdaptsLoad(t *testing.T) {
+	store := loadOnlyStore{history: eventsource.History{{Version: 1}, {Version: 2}}}
+
+	cursor, err := eventsource.Stream(context.Background(), store, "id", 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer cursor.Close()
+
+	var versions []int64
+	for cursor.Next() {
+		versions = append(versions, cursor.Record().Version)
+	}
+
+	if err := cursor.Err(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(versions) != 2 || versions[0] != 1 || versions[1] != 2 {
+		t.Fatalf("expected versions [1 2] but found %v", versions)
+	}
+}
+
+// TestHistoryCursorCanceled asserts a Cursor stops once its context is done.
+func TestHistoryCursorCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cursor := eventsource.NewHistoryCursor(ctx, eventsource.History{{Version: 1}, {Version: 2}})
+	defer cursor.Close()
+
+	if !cursor.Next() {
+		t.Fatalf("expected a record before cancellation")
+	}
+
+	cancel()
+
+	if cursor.Next() {
+		t.Fatalf("expected iteration to stop after cancellation")
+	}
+	if cursor.Err() != context.Canceled {
+		t.Fatalf("expected %v but found %v", context.Canceled, cursor.Err())
+	}
+}
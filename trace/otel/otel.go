@@ -0,0 +1,85 @@
+// Package otel adapts an OpenTelemetry tracer to the eventsource trace.Tracer
+// so repository, serializer and store spans join existing OpenTelemetry
+// traces.
+//
+//	tracer := otel.New(otelapi.Tracer("eventsource"))
+//	repo, err := repository.New(&Person{}, events, repository.WithTracer(tracer))
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/aarongreenlee/eventsource/trace"
+)
+
+// Tracer implements trace.Tracer with an OpenTelemetry tracer. Spans are
+// propagated through the context as OpenTelemetry spans so spans started by
+// other instrumentation become parents and children as expected.
+type Tracer struct {
+	tracer oteltrace.Tracer
+}
+
+// New adapts the OpenTelemetry tracer provided.
+func New(tracer oteltrace.Tracer) *Tracer {
+	return &Tracer{tracer: tracer}
+}
+
+// Start implements the trace.Tracer interface.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...trace.Attribute) (context.Context, trace.Span) {
+	ctx, span := t.tracer.Start(ctx, name, oteltrace.WithAttributes(convert(attrs)...))
+	return ctx, spanAdapter{span: span}
+}
+
+// spanAdapter implements trace.Span with an OpenTelemetry span.
+type spanAdapter struct {
+	span oteltrace.Span
+}
+
+// SetAttributes implements the trace.Span interface.
+func (s spanAdapter) SetAttributes(attrs ...trace.Attribute) {
+	s.span.SetAttributes(convert(attrs)...)
+}
+
+// RecordError implements the trace.Span interface.
+func (s spanAdapter) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End implements the trace.Span interface.
+func (s spanAdapter) End() {
+	s.span.End()
+}
+
+// convert maps attribute values onto the OpenTelemetry attribute types,
+// formatting values of any other type as strings.
+func convert(attrs []trace.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		switch v := a.Value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(a.Key, v))
+		case int:
+			kvs = append(kvs, attribute.Int(a.Key, v))
+		case int64:
+			kvs = append(kvs, attribute.Int64(a.Key, v))
+		case float64:
+			kvs = append(kvs, attribute.Float64(a.Key, v))
+		case bool:
+			kvs = append(kvs, attribute.Bool(a.Key, v))
+		case fmt.Stringer:
+			kvs = append(kvs, attribute.Stringer(a.Key, v))
+		default:
+			kvs = append(kvs, attribute.String(a.Key, fmt.Sprint(v)))
+		}
+	}
+	return kvs
+}
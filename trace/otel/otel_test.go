@@ -0,0 +1,42 @@
+package otel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/aarongreenlee/eventsource/trace"
+	"github.com/aarongreenlee/eventsource/trace/otel"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := otel.New(provider.Tracer("test"))
+
+	ctx, parent := tracer.Start(context.Background(), trace.SpanApply,
+		trace.Attribute{Key: trace.AttrAggregateID, Value: "a"},
+	)
+	_, child := tracer.Start(ctx, trace.SpanLoad)
+	child.SetAttributes(trace.Attribute{Key: trace.AttrVersion, Value: int64(3)})
+	child.RecordError(errors.New("boom"))
+	child.End()
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	load, apply := spans[0], spans[1]
+	assert.Equal(t, trace.SpanLoad, load.Name())
+	assert.Equal(t, apply.SpanContext().SpanID(), load.Parent().SpanID())
+	assert.Equal(t, codes.Error, load.Status().Code)
+	assert.Contains(t, load.Attributes(), attribute.Int64(trace.AttrVersion, 3))
+	assert.Contains(t, apply.Attributes(), attribute.String(trace.AttrAggregateID, "a"))
+}
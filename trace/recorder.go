@@ -0,0 +1,101 @@
+package trace
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Recorder is a Tracer which keeps every span in memory so tests may assert
+// against them.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// RecordedSpan is a span started by a Recorder.
+type RecordedSpan struct {
+	Name       string
+	Parent     *RecordedSpan
+	Attributes map[string]interface{}
+	Err        error
+	Started    time.Time
+	Ended      time.Time
+
+	recorder *Recorder
+}
+
+// NewRecorder returns an empty *Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+type spanKey struct{}
+
+// Start implements the Tracer interface.
+func (r *Recorder) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	parent, _ := ctx.Value(spanKey{}).(*RecordedSpan)
+
+	span := &RecordedSpan{
+		Name:       name,
+		Parent:     parent,
+		Attributes: map[string]interface{}{},
+		Started:    time.Now(),
+		recorder:   r,
+	}
+	span.SetAttributes(attrs...)
+
+	r.mu.Lock()
+	r.spans = append(r.spans, span)
+	r.mu.Unlock()
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Spans returns the spans started, in the order they were started.
+func (r *Recorder) Spans() []*RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*RecordedSpan(nil), r.spans...)
+}
+
+// Find returns the first span with the name or nil if there is none.
+func (r *Recorder) Find(name string) *RecordedSpan {
+	for _, span := range r.Spans() {
+		if span.Name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+// SetAttributes implements the Span interface.
+func (s *RecordedSpan) SetAttributes(attrs ...Attribute) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	for _, attr := range attrs {
+		s.Attributes[attr.Key] = attr.Value
+	}
+}
+
+// RecordError implements the Span interface.
+func (s *RecordedSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	s.Err = err
+}
+
+// End implements the Span interface.
+func (s *RecordedSpan) End() {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	s.Ended = time.Now()
+}
@@ -0,0 +1,58 @@
+package trace
+
+import (
+	"context"
+
+	es "github.com/aarongreenlee/eventsource"
+)
+
+// Serializer decorates an es.Serializer so each marshal and unmarshal is
+// traced as a child of the span carried by the context. Calls made without a
+// context, through es.Serializer rather than es.ContextSerializer, are traced
+// as new root spans.
+func Serializer(serializer es.Serializer, tracer Tracer) es.Serializer {
+	return &tracedSerializer{Serializer: serializer, tracer: tracer}
+}
+
+// tracedSerializer implements the serializer returned by Serializer.
+type tracedSerializer struct {
+	es.Serializer
+	tracer Tracer
+}
+
+// MarshalEvent implements the es.Serializer interface.
+func (s *tracedSerializer) MarshalEvent(event es.Event) (es.Record, error) {
+	return s.MarshalEventContext(context.Background(), event)
+}
+
+// UnmarshalEvent implements the es.Serializer interface.
+func (s *tracedSerializer) UnmarshalEvent(record es.Record) (es.Event, error) {
+	return s.UnmarshalEventContext(context.Background(), record)
+}
+
+// MarshalEventContext implements the es.ContextSerializer interface.
+func (s *tracedSerializer) MarshalEventContext(ctx context.Context, event es.Event) (es.Record, error) {
+	ctx, span := s.tracer.Start(ctx, SpanSerialize)
+	defer span.End()
+
+	if event != nil {
+		span.SetAttributes(Attribute{Key: AttrEventType, Value: event.EventType()})
+	}
+
+	record, err := es.MarshalEvent(ctx, s.Serializer, event)
+	span.RecordError(err)
+	return record, err
+}
+
+// UnmarshalEventContext implements the es.ContextSerializer interface.
+func (s *tracedSerializer) UnmarshalEventContext(ctx context.Context, record es.Record) (es.Event, error) {
+	ctx, span := s.tracer.Start(ctx, SpanDecode, Attribute{Key: AttrVersion, Value: record.Version})
+	defer span.End()
+
+	event, err := es.UnmarshalEvent(ctx, s.Serializer, record)
+	span.RecordError(err)
+	if err == nil {
+		span.SetAttributes(Attribute{Key: AttrEventType, Value: event.EventType()})
+	}
+	return event, err
+}
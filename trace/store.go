@@ -0,0 +1,158 @@
+package trace
+
+import (
+	"context"
+
+	es "github.com/aarongreenlee/eventsource"
+)
+
+// Store decorates an es.Store so each operation is traced as a child of the
+// span carried by the context. The decorated store implements es.Streamer,
+// streaming through es.Stream, and implements es.TxStore and es.Lister only
+// when the underlying store does.
+func Store(store es.Store, tracer Tracer) es.Store {
+	s := &tracedStore{store: store, tracer: tracer}
+
+	_, tx := store.(es.TxStore)
+	_, lister := store.(es.Lister)
+
+	switch {
+	case tx && lister:
+		return tracedTxLister{s}
+	case tx:
+		return tracedTxStore{s}
+	case lister:
+		return tracedLister{s}
+	default:
+		return s
+	}
+}
+
+// tracedStore implements the store returned by Store.
+type tracedStore struct {
+	store  es.Store
+	tracer Tracer
+}
+
+// Save implements the es.Store interface.
+func (s *tracedStore) Save(ctx context.Context, aggregateID string, records ...es.Record) error {
+	ctx, span := s.tracer.Start(ctx, SpanStore+"save",
+		Attribute{Key: AttrAggregateID, Value: aggregateID},
+		Attribute{Key: AttrEvents, Value: len(records)},
+	)
+	defer span.End()
+
+	err := s.store.Save(ctx, aggregateID, records...)
+	span.RecordError(err)
+	return err
+}
+
+// Load implements the es.Store interface.
+func (s *tracedStore) Load(ctx context.Context, aggregateID string, fromVersion, toVersion int64) (es.History, error) {
+	ctx, span := s.tracer.Start(ctx, SpanStore+"load", Attribute{Key: AttrAggregateID, Value: aggregateID})
+	defer span.End()
+
+	history, err := s.store.Load(ctx, aggregateID, fromVersion, toVersion)
+	span.RecordError(err)
+	span.SetAttributes(Attribute{Key: AttrEvents, Value: len(history)})
+	return history, err
+}
+
+// Stream implements the es.Streamer interface. The span ends when the cursor
+// is closed so it covers reading the records.
+func (s *tracedStore) Stream(ctx context.Context, aggregateID string, fromVersion, toVersion int64) (es.Cursor, error) {
+	ctx, span := s.tracer.Start(ctx, SpanStore+"stream", Attribute{Key: AttrAggregateID, Value: aggregateID})
+
+	cursor, err := es.Stream(ctx, s.store, aggregateID, fromVersion, toVersion)
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return nil, err
+	}
+
+	return &tracedCursor{Cursor: cursor, span: span}, nil
+}
+
+// saveAll traces SaveAll of a store implementing es.TxStore.
+func (s *tracedStore) saveAll(ctx context.Context, streams []es.StreamRecords) error {
+	ctx, span := s.tracer.Start(ctx, SpanStore+"save_all")
+	defer span.End()
+
+	err := s.store.(es.TxStore).SaveAll(ctx, streams...)
+	span.RecordError(err)
+	return err
+}
+
+// list traces List of a store implementing es.Lister.
+func (s *tracedStore) list(ctx context.Context, query es.ListQuery) (es.ListPage, error) {
+	ctx, span := s.tracer.Start(ctx, SpanStore+"list")
+	defer span.End()
+
+	page, err := s.store.(es.Lister).List(ctx, query)
+	span.RecordError(err)
+	return page, err
+}
+
+// tracedTxStore decorates a store implementing es.TxStore.
+type tracedTxStore struct {
+	*tracedStore
+}
+
+// SaveAll implements the es.TxStore interface.
+func (s tracedTxStore) SaveAll(ctx context.Context, streams ...es.StreamRecords) error {
+	return s.saveAll(ctx, streams)
+}
+
+// tracedLister decorates a store implementing es.Lister.
+type tracedLister struct {
+	*tracedStore
+}
+
+// List implements the es.Lister interface.
+func (s tracedLister) List(ctx context.Context, query es.ListQuery) (es.ListPage, error) {
+	return s.list(ctx, query)
+}
+
+// tracedTxLister decorates a store implementing es.TxStore and es.Lister.
+type tracedTxLister struct {
+	*tracedStore
+}
+
+// SaveAll implements the es.TxStore interface.
+func (s tracedTxLister) SaveAll(ctx context.Context, streams ...es.StreamRecords) error {
+	return s.saveAll(ctx, streams)
+}
+
+// List implements the es.Lister interface.
+func (s tracedTxLister) List(ctx context.Context, query es.ListQuery) (es.ListPage, error) {
+	return s.list(ctx, query)
+}
+
+// tracedCursor ends its span once closed.
+type tracedCursor struct {
+	es.Cursor
+	span  Span
+	count int
+	ended bool
+}
+
+// Next implements the es.Cursor interface.
+func (c *tracedCursor) Next() bool {
+	ok := c.Cursor.Next()
+	if ok {
+		c.count++
+	}
+	return ok
+}
+
+// Close implements the es.Cursor interface.
+func (c *tracedCursor) Close() error {
+	err := c.Cursor.Close()
+	if !c.ended {
+		c.ended = true
+		c.span.SetAttributes(Attribute{Key: AttrEvents, Value: c.count})
+		c.span.RecordError(c.Cursor.Err())
+		c.span.End()
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+// Package trace defines the Tracer through which repositories, serializers
+// and stores report spans. The active span is carried by the context.Context
+// passed to each operation so spans started beneath it become its children.
+//
+// A Tracer which records spans in memory is provided for tests and the otel
+// sub-package adapts an OpenTelemetry tracer.
+package trace
+
+import "context"
+
+// Names of the spans started by the repository and the decorators.
+const (
+	SpanApply     = "eventsource.apply"
+	SpanSimulate  = "eventsource.simulate"
+	SpanLoad      = "eventsource.load"
+	SpanFold      = "eventsource.fold"
+	SpanHandle    = "eventsource.handle"
+	SpanValidate  = "eventsource.validate"
+	SpanMarshal   = "eventsource.marshal"
+	SpanSave      = "eventsource.save"
+	SpanPublish   = "eventsource.publish"
+	SpanSerialize = "eventsource.serializer.marshal"
+	SpanDecode    = "eventsource.serializer.unmarshal"
+
+	// SpanStore prefixes the name of the store operation, such as
+	// eventsource.store.save.
+	SpanStore = "eventsource.store."
+)
+
+// Keys of the attributes attached to spans.
+const (
+	AttrAggregateID   = "eventsource.aggregate_id"
+	AttrAggregateType = "eventsource.aggregate_type"
+	AttrCommandType   = "eventsource.command_type"
+	AttrEventType     = "eventsource.event_type"
+	AttrEvents        = "eventsource.events"
+	AttrVersion       = "eventsource.version"
+)
+
+// Attribute is a key/value pair attached to a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Tracer starts spans.
+type Tracer interface {
+	// Start begins a span as a child of the span carried by ctx, if any, and
+	// returns a context carrying the new span.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is a timed operation. A span must be ended.
+type Span interface {
+	// SetAttributes attaches attributes to the span.
+	SetAttributes(attrs ...Attribute)
+
+	// RecordError marks the span as failed. A nil error is ignored.
+	RecordError(err error)
+
+	// End completes the span.
+	End()
+}
+
+// Nop is a Tracer whose spans do nothing.
+type Nop struct{}
+
+// Start implements the Tracer interface.
+func (Nop) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...Attribute) {}
+func (nopSpan) RecordError(error)          {}
+func (nopSpan) End()                       {}
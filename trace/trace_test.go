@@ -0,0 +1,142 @@
+package trace_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	es "github.com/aarongreenlee/eventsource"
+	"github.com/aarongreenlee/eventsource/repository"
+	"github.com/aarongreenlee/eventsource/serializer/gob"
+	"github.com/aarongreenlee/eventsource/serializer/serializertest"
+	"github.com/aarongreenlee/eventsource/store/memory"
+	"github.com/aarongreenlee/eventsource/store/storetest"
+	"github.com/aarongreenlee/eventsource/trace"
+)
+
+// Note is the aggregate traced by the tests.
+type Note struct {
+	es.EventModel
+	Text string
+}
+
+func (n *Note) On(event es.Event) error {
+	n.Text = event.(*Noted).Text
+	return nil
+}
+
+func (n *Note) Apply(_ context.Context, command es.Command) ([]es.Event, error) {
+	return []es.Event{&Noted{Text: command.(Write).Text}}, nil
+}
+
+// Write asks a Note to change its text.
+type Write struct {
+	es.CommandModel
+	Text string
+}
+
+// Noted records the text of a Note.
+type Noted struct {
+	es.EventModel
+	Text string
+}
+
+func (Noted) EventType() string { return "noted" }
+
+// Erased records that the text of a Note was removed.
+type Erased struct {
+	es.EventModel
+}
+
+func (Erased) EventType() string { return "erased" }
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) es.Store {
+		return trace.Store(memory.New(), trace.Nop{})
+	})
+}
+
+// basicStore hides the optional interfaces of the memory store.
+type basicStore struct {
+	es.Store
+}
+
+// TestStoreCapabilities asserts the decorated store only implements the
+// optional interfaces of the underlying store.
+func TestStoreCapabilities(t *testing.T) {
+	store := trace.Store(memory.New(), trace.Nop{})
+	assert.Implements(t, (*es.TxStore)(nil), store)
+	assert.Implements(t, (*es.Lister)(nil), store)
+
+	store = trace.Store(basicStore{Store: memory.New()}, trace.Nop{})
+	_, ok := store.(es.TxStore)
+	assert.False(t, ok)
+	_, ok = store.(es.Lister)
+	assert.False(t, ok)
+
+	repo, err := repository.New(&Note{}, []es.Event{&Noted{}}, repository.WithStore(store))
+	require.NoError(t, err)
+
+	_, err = repo.UnitOfWork().Add(Write{CommandModel: es.CommandModel{ID: "a"}}).Commit(context.Background())
+	assert.True(t, errors.Is(err, es.ErrTxUnsupported))
+}
+
+func TestSerializer(t *testing.T) {
+	serializertest.Run(t, func() es.Serializer {
+		return trace.Serializer(&gob.Serializer{}, trace.Nop{})
+	},
+		&Noted{EventModel: es.EventModel{ID: "a", Version: 1, At: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}, Text: "hello"},
+		&Erased{EventModel: es.EventModel{ID: "a", Version: 2, At: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}},
+	)
+}
+
+// TestRepository asserts the spans of an applied command are nested beneath
+// the span carried by the context.
+func TestRepository(t *testing.T) {
+	recorder := trace.NewRecorder()
+
+	repo, err := repository.New(&Note{}, []es.Event{&Noted{}},
+		repository.WithStamping(),
+		repository.WithTracer(recorder),
+		repository.WithStore(trace.Store(memory.New(), recorder)),
+		repository.WithSerializer(trace.Serializer(&gob.Serializer{}, recorder)),
+	)
+	require.NoError(t, err)
+
+	ctx, request := recorder.Start(context.Background(), "request")
+	_, err = repo.Apply(ctx, Write{CommandModel: es.CommandModel{ID: "a"}, Text: "first"})
+	require.NoError(t, err)
+	_, err = repo.Apply(ctx, Write{CommandModel: es.CommandModel{ID: "a"}, Text: "second"})
+	require.NoError(t, err)
+	request.End()
+
+	parents := map[string]string{}
+	for _, span := range recorder.Spans() {
+		assert.False(t, span.Ended.IsZero(), "span %s was not ended", span.Name)
+		if span.Parent != nil {
+			parents[span.Name] = span.Parent.Name
+		}
+	}
+
+	assert.Equal(t, map[string]string{
+		trace.SpanApply:            "request",
+		trace.SpanLoad:             trace.SpanApply,
+		trace.SpanFold:             trace.SpanLoad,
+		trace.SpanStore + "stream": trace.SpanFold,
+		trace.SpanDecode:           trace.SpanFold,
+		trace.SpanHandle:           trace.SpanApply,
+		trace.SpanValidate:         trace.SpanApply,
+		trace.SpanSave:             trace.SpanApply,
+		trace.SpanMarshal:          trace.SpanSave,
+		trace.SpanSerialize:        trace.SpanMarshal,
+		trace.SpanStore + "save":   trace.SpanSave,
+	}, parents)
+
+	apply := recorder.Find(trace.SpanApply)
+	assert.Equal(t, "a", apply.Attributes[trace.AttrAggregateID])
+	assert.Equal(t, "Note", apply.Attributes[trace.AttrAggregateType])
+}